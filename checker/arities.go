@@ -0,0 +1,101 @@
+package checker
+
+import "strconv"
+
+// arity is how many arguments a builtin takes. max is -1 when there is no
+// limit.
+type arity struct {
+	min, max int
+}
+
+// want describes a the way the builtins' own errors do, as in "want=1 or 2".
+func (a arity) want() string {
+	switch {
+	case a.max < 0:
+		return "want at least " + strconv.Itoa(a.min)
+	case a.min == a.max:
+		return "want=" + strconv.Itoa(a.min)
+	case a.min+1 == a.max:
+		return "want=" + strconv.Itoa(a.min) + " or " + strconv.Itoa(a.max)
+	default:
+		return "want=" + strconv.Itoa(a.min) + " to " + strconv.Itoa(a.max)
+	}
+}
+
+// arities holds every builtin function, with module members by their
+// qualified names.
+var arities = map[string]arity{
+	"len":     {1, 1},
+	"first":   {1, 1},
+	"last":    {1, 1},
+	"rest":    {1, 1},
+	"push":    {2, 2},
+	"zip":     {1, -1},
+	"keys":    {1, 1},
+	"values":  {1, 1},
+	"entries": {1, 1},
+	"has":     {2, 2},
+	"delete":  {2, 2},
+	"merge":   {1, -1},
+	"bytes":   {1, 1},
+	"runes":   {1, 1},
+	"sprintf": {1, -1},
+	"printf":  {1, -1},
+	"puts":    {0, -1},
+	"type":    {1, 1},
+
+	"str":   {1, 1},
+	"int":   {1, 1},
+	"float": {1, 1},
+	"bool":  {1, 1},
+
+	"isInt":      {1, 1},
+	"isFloat":    {1, 1},
+	"isNumber":   {1, 1},
+	"isString":   {1, 1},
+	"isBool":     {1, 1},
+	"isArray":    {1, 1},
+	"isHash":     {1, 1},
+	"isNull":     {1, 1},
+	"isFunction": {1, 1},
+
+	"map":    {2, 2},
+	"filter": {2, 2},
+	"reduce": {2, 3},
+	"sort":   {1, 2},
+	"find":   {2, 2},
+	"any":    {2, 2},
+	"all":    {2, 2},
+
+	"string.split":      {1, 2},
+	"string.join":       {2, 2},
+	"string.trim":       {1, 2},
+	"string.upper":      {1, 1},
+	"string.lower":      {1, 1},
+	"string.replace":    {3, 3},
+	"string.contains":   {2, 2},
+	"string.startsWith": {2, 2},
+	"string.endsWith":   {2, 2},
+	"string.indexOf":    {2, 2},
+	"string.repeat":     {2, 2},
+	"string.padLeft":    {2, 3},
+	"string.padRight":   {2, 3},
+
+	"math.abs":    {1, 1},
+	"math.min":    {1, -1},
+	"math.max":    {1, -1},
+	"math.clamp":  {3, 3},
+	"math.floor":  {1, 1},
+	"math.ceil":   {1, 1},
+	"math.round":  {1, 1},
+	"math.sqrt":   {1, 1},
+	"math.sin":    {1, 1},
+	"math.cos":    {1, 1},
+	"math.atan2":  {2, 2},
+	"math.lerp":   {3, 3},
+	"math.random": {0, 2},
+	"math.seed":   {1, 1},
+
+	"json.parse":     {1, 1},
+	"json.stringify": {1, 2},
+}
@@ -0,0 +1,430 @@
+// Package checker finds mistakes in a program without running it, so that
+// a typo doesn't wait for its code path to run mid-game. It reports names
+// that aren't defined, bindings that are never used or that shadow others,
+// builtins called with the wrong number of arguments and code that can't
+// run because it follows a return.
+package checker
+
+import (
+	"cathon/ast"
+	"cathon/evaluator"
+	"cathon/object"
+	"cathon/token"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type Severity string
+
+const (
+	// Error marks code that fails whenever it runs.
+	Error Severity = "error"
+	// Warning marks code that runs but is likely a mistake.
+	Warning Severity = "warning"
+)
+
+// Codes name the kinds of diagnostics, so tools can filter them. SYNTAX
+// is for parser errors, which tools report alongside the checker's.
+const (
+	SYNTAX      = "syntax"
+	UNDEFINED   = "undefined"
+	ARITY       = "arity"
+	UNUSED      = "unused"
+	SHADOW      = "shadow"
+	UNREACHABLE = "unreachable"
+)
+
+type Diagnostic struct {
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	Message  string   `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s (%s)", d.Line, d.Column, d.Severity, d.Message, d.Code)
+}
+
+// Check checks program and returns what it finds, ordered by position.
+// globals are the names the host defines besides the builtins, such as args
+// for scripts started by cathon run.
+//
+// Names starting with an underscore are never reported as unused.
+func Check(program *ast.Program, globals ...string) []Diagnostic {
+	return Analyze(program, globals...).Diagnostics
+}
+
+// Info is what Analyze learns about a program, for editors.
+type Info struct {
+	// Diagnostics are what Check returns.
+	Diagnostics []Diagnostic
+
+	// Definitions maps the identifiers that name a let or a parameter to
+	// the identifier that declares it. Declarations map to themselves.
+	Definitions map[*ast.Identifier]*ast.Identifier
+
+	// Builtins maps the identifiers that name a builtin or a module to its
+	// name. The member of a module, such as sqrt in math.sqrt, maps to
+	// "math.sqrt".
+	Builtins map[*ast.Identifier]string
+
+	// Scopes are the program's scope and those of its functions, in the
+	// order they start.
+	Scopes []*Scope
+}
+
+// Analyze checks program as Check does and also resolves its names.
+func Analyze(program *ast.Program, globals ...string) *Info {
+	c := &checker{
+		builtins: evaluator.New().Builtins(),
+		globals:  make(map[string]bool),
+		info: &Info{
+			Definitions: make(map[*ast.Identifier]*ast.Identifier),
+			Builtins:    make(map[*ast.Identifier]string),
+		},
+	}
+	for _, name := range globals {
+		c.globals[name] = true
+	}
+
+	c.checkScope(c.newScope(nil), program.Statements)
+
+	sort.SliceStable(c.info.Diagnostics, func(i, j int) bool {
+		a, b := c.info.Diagnostics[i], c.info.Diagnostics[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	sort.SliceStable(c.info.Scopes, func(i, j int) bool {
+		return before(c.info.Scopes[i].Start, c.info.Scopes[j].Start)
+	})
+	return c.info
+}
+
+// NamesAt returns the lets and parameters visible at line and column,
+// innermost first. Of two with the same name only the inner one is seen.
+func (info *Info) NamesAt(line, column int) []*ast.Identifier {
+	pos := token.Token{Line: line, Column: column}
+
+	var inner *Scope
+	for _, s := range info.Scopes {
+		if s.contains(pos) {
+			inner = s
+		}
+	}
+
+	seen := make(map[string]bool)
+	names := []*ast.Identifier{}
+	for s := inner; s != nil; s = s.Outer {
+		for _, b := range s.order {
+			if !seen[b.name.Value] {
+				seen[b.name.Value] = true
+				names = append(names, b.name)
+			}
+		}
+	}
+	return names
+}
+
+// A Scope holds the bindings of a program or of one function call, as
+// object.NewEnclosedEnvironment does at run time. Blocks don't get their
+// own scope, since if and else evaluate in the environment around them.
+type Scope struct {
+	Outer *Scope
+	// Start and End are the fn token and the closing brace of a function.
+	// They are zero for the program, whose scope holds everything.
+	Start, End token.Token
+
+	bindings map[string]*binding
+	order    []*binding
+
+	// funcs are the function literals written in this scope. Their bodies
+	// run after the scope's lets are done, at the earliest when they are
+	// called, so they are checked once the whole scope has been.
+	funcs []*ast.FunctionLiteral
+}
+
+type binding struct {
+	name  *ast.Identifier
+	param bool
+	used  bool
+}
+
+func (c *checker) newScope(outer *Scope) *Scope {
+	s := &Scope{Outer: outer, bindings: make(map[string]*binding)}
+	c.info.Scopes = append(c.info.Scopes, s)
+	return s
+}
+
+func (s *Scope) contains(pos token.Token) bool {
+	if s.Outer == nil {
+		return true
+	}
+	return !before(pos, s.Start) && (s.End.Type == token.EOF || !before(s.End, pos))
+}
+
+// before reports whether a starts before b.
+func before(a, b token.Token) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+}
+
+func (s *Scope) lookup(name string) *binding {
+	for ; s != nil; s = s.Outer {
+		if b, ok := s.bindings[name]; ok {
+			return b
+		}
+	}
+	return nil
+}
+
+type checker struct {
+	builtins map[string]object.Object
+	globals  map[string]bool
+	info     *Info
+}
+
+func (c *checker) report(tok token.Token, severity Severity, code, format string, a ...interface{}) {
+	c.info.Diagnostics = append(c.info.Diagnostics, Diagnostic{
+		Line:     tok.Line,
+		Column:   tok.Column,
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, a...),
+	})
+}
+
+func (c *checker) checkScope(s *Scope, statements []ast.Statement) {
+	c.checkStatements(s, statements)
+
+	for _, fn := range s.funcs {
+		inner := c.newScope(s)
+		inner.Start, inner.End = fn.Token, fn.Body.End
+		for _, param := range fn.Parameters {
+			c.declare(inner, param, true)
+		}
+		c.checkScope(inner, fn.Body.Statements)
+	}
+
+	for _, b := range s.order {
+		if b.used || strings.HasPrefix(b.name.Value, "_") {
+			continue
+		}
+		if b.param {
+			c.report(b.name.Token, Warning, UNUSED, "parameter %s is never used", b.name.Value)
+		} else {
+			c.report(b.name.Token, Warning, UNUSED, "%s is declared but never used", b.name.Value)
+		}
+	}
+}
+
+// declare binds name in s. A second let of a name in the same scope
+// replaces its value at run time, so it is the same binding.
+func (c *checker) declare(s *Scope, name *ast.Identifier, param bool) {
+	if b, ok := s.bindings[name.Value]; ok {
+		c.info.Definitions[name] = b.name
+		return
+	}
+	c.info.Definitions[name] = name
+
+	if outer := s.Outer.lookup(name.Value); outer != nil {
+		c.report(name.Token, Warning, SHADOW, "%s shadows the declaration at %d:%d",
+			name.Value, outer.name.Token.Line, outer.name.Token.Column)
+	} else if _, ok := c.builtins[name.Value]; ok {
+		c.report(name.Token, Warning, SHADOW, "%s shadows the builtin %s", name.Value, name.Value)
+	}
+
+	b := &binding{name: name, param: param}
+	s.bindings[name.Value] = b
+	s.order = append(s.order, b)
+}
+
+func (c *checker) checkStatements(s *Scope, statements []ast.Statement) {
+	terminated, reported := false, false
+	for _, stmt := range statements {
+		if terminated && !reported {
+			c.report(statementToken(stmt), Warning, UNREACHABLE, "unreachable code")
+			reported = true
+		}
+		c.checkStatement(s, stmt)
+		terminated = terminated || terminates(stmt)
+	}
+}
+
+func (c *checker) checkStatement(s *Scope, stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		c.checkExpression(s, stmt.Value)
+		c.declare(s, stmt.Name, false)
+	case *ast.ReturnStatement:
+		c.checkExpression(s, stmt.ReturnValue)
+	case *ast.ExpressionStatement:
+		c.checkExpression(s, stmt.Expression)
+	case *ast.BlockStatement:
+		c.checkStatements(s, stmt.Statements)
+	}
+}
+
+func (c *checker) checkExpression(s *Scope, expr ast.Expression) {
+	switch expr := expr.(type) {
+	case *ast.Identifier:
+		c.use(s, expr)
+
+	case *ast.PrefixExpression:
+		c.checkExpression(s, expr.Right)
+
+	case *ast.InfixExpression:
+		c.checkExpression(s, expr.Left)
+		c.checkExpression(s, expr.Right)
+
+	case *ast.IfExpression:
+		c.checkExpression(s, expr.Condition)
+		c.checkStatements(s, expr.Consequence.Statements)
+		if expr.Alternative != nil {
+			c.checkStatements(s, expr.Alternative.Statements)
+		}
+
+	case *ast.FunctionLiteral:
+		s.funcs = append(s.funcs, expr)
+
+	case *ast.CallExpression:
+		c.checkCall(s, expr)
+		c.checkExpression(s, expr.Function)
+		for _, arg := range expr.Arguments {
+			c.checkExpression(s, arg)
+		}
+
+	case *ast.ArrayLiteral:
+		for _, el := range expr.Elements {
+			c.checkExpression(s, el)
+		}
+
+	case *ast.IndexExpression:
+		c.checkExpression(s, expr.Left)
+		c.checkExpression(s, expr.Index)
+
+	case *ast.SliceExpression:
+		c.checkExpression(s, expr.Left)
+		c.checkExpression(s, expr.Start)
+		c.checkExpression(s, expr.End)
+		c.checkExpression(s, expr.Step)
+
+	case *ast.HashLiteral:
+		for _, key := range expr.Keys {
+			c.checkExpression(s, key)
+			c.checkExpression(s, expr.Pairs[key])
+		}
+
+	case *ast.DotExpression:
+		c.checkExpression(s, expr.Left)
+		if module := c.module(s, expr.Left); module != nil {
+			if _, ok := module.Members[expr.Property.Value]; ok {
+				c.info.Builtins[expr.Property] = module.Name + "." + expr.Property.Value
+			} else {
+				c.report(expr.Property.Token, Error, UNDEFINED, "module %s has no member %s",
+					module.Name, expr.Property.Value)
+			}
+		}
+
+	case *ast.AssignExpression:
+		c.checkExpression(s, expr.Target)
+		c.checkExpression(s, expr.Value)
+	}
+}
+
+func (c *checker) use(s *Scope, ident *ast.Identifier) {
+	if b := s.lookup(ident.Value); b != nil {
+		b.used = true
+		c.info.Definitions[ident] = b.name
+		return
+	}
+	if _, ok := c.builtins[ident.Value]; ok {
+		c.info.Builtins[ident] = ident.Value
+		return
+	}
+	if c.globals[ident.Value] {
+		return
+	}
+	c.report(ident.Token, Error, UNDEFINED, "identifier not found: %s", ident.Value)
+}
+
+// module returns the module that expr names, if it names one that no
+// binding shadows.
+func (c *checker) module(s *Scope, expr ast.Expression) *object.Module {
+	ident, ok := expr.(*ast.Identifier)
+	if !ok || s.lookup(ident.Value) != nil {
+		return nil
+	}
+	module, _ := c.builtins[ident.Value].(*object.Module)
+	return module
+}
+
+// checkCall checks the number of arguments of calls to builtins, such as
+// len(x) and string.split(x).
+func (c *checker) checkCall(s *Scope, call *ast.CallExpression) {
+	var name string
+	var tok token.Token
+
+	switch fn := call.Function.(type) {
+	case *ast.Identifier:
+		if s.lookup(fn.Value) != nil {
+			return
+		}
+		name, tok = fn.Value, fn.Token
+	case *ast.DotExpression:
+		module := c.module(s, fn.Left)
+		if module == nil {
+			return
+		}
+		name, tok = module.Name+"."+fn.Property.Value, fn.Property.Token
+	default:
+		return
+	}
+
+	a, ok := arities[name]
+	if !ok {
+		return
+	}
+	if got := len(call.Arguments); got < a.min || (a.max >= 0 && got > a.max) {
+		c.report(tok, Error, ARITY, "wrong number of arguments to %s. got=%d, %s",
+			name, got, a.want())
+	}
+}
+
+// terminates reports whether nothing after stmt in the same block runs.
+func terminates(stmt ast.Statement) bool {
+	switch stmt := stmt.(type) {
+	case *ast.ReturnStatement:
+		return true
+	case *ast.ExpressionStatement:
+		ie, ok := stmt.Expression.(*ast.IfExpression)
+		return ok && ie.Alternative != nil &&
+			blockTerminates(ie.Consequence) && blockTerminates(ie.Alternative)
+	}
+	return false
+}
+
+func blockTerminates(block *ast.BlockStatement) bool {
+	for _, stmt := range block.Statements {
+		if terminates(stmt) {
+			return true
+		}
+	}
+	return false
+}
+
+func statementToken(stmt ast.Statement) token.Token {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		return stmt.Token
+	case *ast.ReturnStatement:
+		return stmt.Token
+	case *ast.ExpressionStatement:
+		return stmt.Token
+	case *ast.BlockStatement:
+		return stmt.Token
+	}
+	return token.Token{}
+}
@@ -0,0 +1,176 @@
+package checker
+
+import (
+	"cathon/evaluator"
+	"cathon/lexer"
+	"cathon/object"
+	"cathon/parser"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 1; puts(x);", nil},
+		{"puts(y);", []string{"1:6: error: identifier not found: y (undefined)"}},
+		{"puts(x); let x = 1; x", []string{"1:6: error: identifier not found: x (undefined)"}},
+		{"let x = 1;\nlet x = x + 1;\nx", nil},
+		{"let unused = 1;", []string{"1:5: warning: unused is declared but never used (unused)"}},
+		{"let _ = 1; let _later = 2;", nil},
+		{"let f = fn(a, b) { a }; f(1, 2)",
+			[]string{"1:15: warning: parameter b is never used (unused)"}},
+		// Functions see lets that come after them, as they run later.
+		{"let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } };\n" +
+			"let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } };\n" +
+			"even(4)", nil},
+		{"let x = 1; let f = fn() { let x = 2; x }; f() + x",
+			[]string{"1:31: warning: x shadows the declaration at 1:5 (shadow)"}},
+		{"let f = fn(x) { fn(x) { x } }; f(1)(2)",
+			[]string{"1:12: warning: parameter x is never used (unused)",
+				"1:20: warning: x shadows the declaration at 1:12 (shadow)"}},
+		{"let len = fn(x) { x }; len(1, 2)",
+			[]string{"1:5: warning: len shadows the builtin len (shadow)"}},
+		{"len(1, 2)", []string{"1:1: error: wrong number of arguments to len. got=2, want=1 (arity)"}},
+		{"reduce([1])", []string{"1:1: error: wrong number of arguments to reduce. got=1, want=2 or 3 (arity)"}},
+		{"zip()", []string{"1:1: error: wrong number of arguments to zip. got=0, want at least 1 (arity)"}},
+		{"math.random(1, 2, 3)",
+			[]string{"1:6: error: wrong number of arguments to math.random. got=3, want=0 to 2 (arity)"}},
+		{"math.sqr(2)", []string{"1:6: error: module math has no member sqr (undefined)"}},
+		{"let math = {}; math.sqr(2)",
+			[]string{"1:5: warning: math shadows the builtin math (shadow)"}},
+		{"let f = fn() { return 1; puts(2); puts(3) }; f()",
+			[]string{"1:26: warning: unreachable code (unreachable)"}},
+		{"let f = fn(c) { if (c) { return 1 } else { return 2 }; 3 }; f(true)",
+			[]string{"1:56: warning: unreachable code (unreachable)"}},
+		{"let f = fn(c) { if (c) { return 1 }; 2 }; f(true)", nil},
+		{"args[0]", nil},
+		{"let h = {key: 1}; h.size = missing",
+			[]string{"1:10: error: identifier not found: key (undefined)",
+				"1:28: error: identifier not found: missing (undefined)"}},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser errors for %q: %v", tt.input, p.Errors())
+		}
+
+		var got []string
+		for _, d := range Check(program, "args") {
+			got = append(got, d.String())
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("wrong diagnostics for %q. expected=\n%s\ngot=\n%s",
+				tt.input, strings.Join(tt.expected, "\n"), strings.Join(got, "\n"))
+		}
+	}
+}
+
+func TestAritiesCoverBuiltins(t *testing.T) {
+	for name, builtin := range evaluator.New().Builtins() {
+		switch builtin := builtin.(type) {
+		case *object.Builtin:
+			if _, ok := arities[name]; !ok {
+				t.Errorf("no arity for builtin %s", name)
+			}
+		case *object.Module:
+			for member, value := range builtin.Members {
+				if _, ok := value.(*object.Builtin); !ok {
+					continue
+				}
+				if _, ok := arities[name+"."+member]; !ok {
+					t.Errorf("no arity for builtin %s.%s", name, member)
+				}
+			}
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	input := `let limit = 10;
+let clampAll = fn(xs, lo) {
+  let hi = limit;
+  map(xs, fn(x) { math.clamp(x, lo, hi) })
+};
+clampAll([1, 20], 0)`
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	info := Analyze(program)
+
+	if len(info.Diagnostics) != 0 {
+		t.Errorf("unexpected diagnostics: %v", info.Diagnostics)
+	}
+
+	definitions := map[string]string{}
+	for use, decl := range info.Definitions {
+		key := fmt.Sprintf("%s@%d:%d", use.Value, use.Token.Line, use.Token.Column)
+		definitions[key] = fmt.Sprintf("%d:%d", decl.Token.Line, decl.Token.Column)
+	}
+	for use, decl := range map[string]string{
+		"limit@3:12":   "1:5",
+		"lo@4:33":      "2:23",
+		"hi@4:37":      "3:7",
+		"x@4:30":       "4:14",
+		"clampAll@6:1": "2:5",
+		"clampAll@2:5": "2:5",
+	} {
+		if definitions[use] != decl {
+			t.Errorf("wrong definition of %s. expected=%s, got=%q", use, decl, definitions[use])
+		}
+	}
+
+	builtins := map[string]bool{}
+	for ident, name := range info.Builtins {
+		builtins[fmt.Sprintf("%s@%d:%d", name, ident.Token.Line, ident.Token.Column)] = true
+	}
+	for _, want := range []string{"map@4:3", "math@4:19", "math.clamp@4:24"} {
+		if !builtins[want] {
+			t.Errorf("builtin %s not found. got=%v", want, builtins)
+		}
+	}
+
+	tests := []struct {
+		line, column int
+		expected     string
+	}{
+		{1, 1, "limit clampAll"},
+		{3, 3, "xs lo hi limit clampAll"},
+		{4, 30, "x xs lo hi limit clampAll"},
+		{6, 1, "limit clampAll"},
+	}
+	for _, tt := range tests {
+		var names []string
+		for _, name := range info.NamesAt(tt.line, tt.column) {
+			names = append(names, name.Value)
+		}
+		if got := strings.Join(names, " "); got != tt.expected {
+			t.Errorf("wrong names at %d:%d. expected=%q, got=%q", tt.line, tt.column, tt.expected, got)
+		}
+	}
+}
+
+func TestCheckIncompletePrograms(t *testing.T) {
+	inputs := []string{
+		"let = 1; x",
+		"let f = fn(x) {",
+		"if (x) { return",
+		"math.",
+		"let h = {a: };",
+		"f(1,",
+		"a[1:",
+	}
+
+	for _, input := range inputs {
+		program := parser.New(lexer.New(input)).ParseProgram()
+		Analyze(program) // must not panic
+	}
+}
@@ -0,0 +1,588 @@
+// Package compiler lowers a program to the bytecode of package code, which
+// package vm runs. Names are resolved once, when the program is compiled,
+// where the evaluator looks them up in environments each time they run.
+package compiler
+
+import (
+	"cathon/ast"
+	"cathon/code"
+	"cathon/evaluator"
+	"cathon/object"
+	"fmt"
+	"sort"
+)
+
+// Bytecode is a compiled program.
+type Bytecode struct {
+	Instructions code.Instructions
+	Constants    []object.Object
+	// Globals names the global slots and Builtins the builtins, by the
+	// indexes the instructions use.
+	Globals  []string
+	Builtins []string
+}
+
+type EmittedInstruction struct {
+	Opcode   code.Opcode
+	Position int
+}
+
+// A CompilationScope holds the instructions of the program or of one
+// function while they are compiled.
+type CompilationScope struct {
+	instructions        code.Instructions
+	lastInstruction     EmittedInstruction
+	previousInstruction EmittedInstruction
+
+	// locals are the positions of the OpGetLocal and OpSetLocal of the
+	// scope. Those whose slot a function inside captures become OpGetCell
+	// and OpSetCell once the functions have been compiled.
+	locals []int
+
+	// funcs are the function literals written in the scope. Their bodies
+	// run after the scope's lets are done, at the earliest when they are
+	// called, so they are compiled once the whole scope has been.
+	funcs []pendingFunction
+}
+
+// pendingFunction is a function literal whose body is yet to be compiled
+// into fn, which is already in the constant pool.
+type pendingFunction struct {
+	literal *ast.FunctionLiteral
+	fn      *object.CompiledFunction
+}
+
+type Compiler struct {
+	constants   []object.Object
+	symbolTable *SymbolTable
+
+	scopes     []CompilationScope
+	scopeIndex int
+
+	builtins []string
+}
+
+// New returns a Compiler for programs using the builtins and modules of
+// evaluator.New.
+func New() *Compiler {
+	var builtins []string
+	for name := range evaluator.New().Builtins() {
+		builtins = append(builtins, name)
+	}
+	sort.Strings(builtins)
+
+	symbolTable := NewSymbolTable()
+	for i, name := range builtins {
+		symbolTable.DefineBuiltin(i, name)
+	}
+
+	return &Compiler{
+		symbolTable: symbolTable,
+		scopes:      []CompilationScope{{}},
+		builtins:    builtins,
+	}
+}
+
+// Compile compiles program.
+func Compile(program *ast.Program) (*Bytecode, error) {
+	c := New()
+	if err := c.Compile(program); err != nil {
+		return nil, err
+	}
+	return c.Bytecode(), nil
+}
+
+func (c *Compiler) Compile(node ast.Node) error {
+	switch node := node.(type) {
+	case *ast.Program:
+		if err := c.compileStatements(node.Statements); err != nil {
+			return err
+		}
+		c.emitReturn()
+		return c.compileFunctions()
+
+	case *ast.ExpressionStatement:
+		if err := c.Compile(node.Expression); err != nil {
+			return err
+		}
+		c.emit(code.OpPop)
+
+	case *ast.LetStatement:
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+		symbol := c.symbolTable.Define(node.Name.Value)
+		if symbol.Scope == GlobalScope {
+			c.emit(code.OpSetGlobal, symbol.Index)
+		} else {
+			c.emitLocal(code.OpSetLocal, symbol.Index)
+		}
+
+	case *ast.ReturnStatement:
+		if err := c.Compile(node.ReturnValue); err != nil {
+			return err
+		}
+		c.emit(code.OpReturnValue)
+
+	case *ast.IntegerLiteral:
+		return c.emitConstant(&object.Integer{Value: node.Value})
+
+	case *ast.FloatLiteral:
+		return c.emitConstant(&object.Float{Value: node.Value})
+
+	case *ast.StringLiteral:
+		return c.emitConstant(&object.String{Value: node.Value})
+
+	case *ast.Boolean:
+		if node.Value {
+			c.emit(code.OpTrue)
+		} else {
+			c.emit(code.OpFalse)
+		}
+
+	case *ast.PrefixExpression:
+		if err := c.Compile(node.Right); err != nil {
+			return err
+		}
+
+		switch node.Operator {
+		case "!":
+			c.emit(code.OpBang)
+		case "-":
+			c.emit(code.OpMinus)
+		default:
+			return fmt.Errorf("unknown operator %s", node.Operator)
+		}
+
+	case *ast.InfixExpression:
+		op, ok := infixOperators[node.Operator]
+		if !ok {
+			return fmt.Errorf("unknown operator %s", node.Operator)
+		}
+
+		if err := c.Compile(node.Left); err != nil {
+			return err
+		}
+		if err := c.Compile(node.Right); err != nil {
+			return err
+		}
+		c.emit(op)
+
+	case *ast.IfExpression:
+		return c.compileIfExpression(node)
+
+	case *ast.Identifier:
+		c.loadSymbol(c.symbolTable.Resolve(node.Value))
+
+	case *ast.FunctionLiteral:
+		fn := &object.CompiledFunction{
+			NumParameters: len(node.Parameters),
+			Parameters:    node.Parameters,
+			Body:          node.Body,
+		}
+		index, err := c.addConstant(fn)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpClosure, index)
+
+		scope := &c.scopes[c.scopeIndex]
+		scope.funcs = append(scope.funcs, pendingFunction{literal: node, fn: fn})
+
+	case *ast.CallExpression:
+		return c.compileCallExpression(node)
+
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			if err := c.Compile(el); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpArray, len(node.Elements))
+
+	case *ast.HashLiteral:
+		for _, key := range node.Keys {
+			if err := c.Compile(key); err != nil {
+				return err
+			}
+			if err := c.Compile(node.Pairs[key]); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpHash, len(node.Keys))
+
+	case *ast.IndexExpression:
+		if err := c.Compile(node.Left); err != nil {
+			return err
+		}
+		if err := c.Compile(node.Index); err != nil {
+			return err
+		}
+		c.emit(code.OpIndex)
+
+	case *ast.SliceExpression:
+		if err := c.Compile(node.Left); err != nil {
+			return err
+		}
+
+		bounds := 0
+		for i, exp := range []ast.Expression{node.Start, node.End, node.Step} {
+			if exp == nil {
+				continue
+			}
+			if err := c.Compile(exp); err != nil {
+				return err
+			}
+			bounds |= 1 << i
+		}
+		c.emit(code.OpSlice, bounds)
+
+	case *ast.DotExpression:
+		if err := c.Compile(node.Left); err != nil {
+			return err
+		}
+		name, err := c.addConstant(&object.String{Value: node.Property.Value})
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpGetField, name)
+
+	case *ast.AssignExpression:
+		target, ok := node.Target.(*ast.DotExpression)
+		if !ok {
+			// The evaluator only finds out when the assignment runs, so
+			// the program must compile and fail there too.
+			message, err := c.addConstant(&object.String{
+				Value: fmt.Sprintf("invalid assignment target: %s", node.Target),
+			})
+			if err != nil {
+				return err
+			}
+			c.emit(code.OpFail, message)
+			return nil
+		}
+
+		if err := c.Compile(target.Left); err != nil {
+			return err
+		}
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+		name, err := c.addConstant(&object.String{Value: target.Property.Value})
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpSetField, name)
+
+	default:
+		return fmt.Errorf("cannot compile %T", node)
+	}
+
+	return nil
+}
+
+var infixOperators = map[string]code.Opcode{
+	"+":  code.OpAdd,
+	"-":  code.OpSub,
+	"*":  code.OpMul,
+	"/":  code.OpDiv,
+	"==": code.OpEqual,
+	"!=": code.OpNotEqual,
+	"<":  code.OpLessThan,
+	">":  code.OpGreaterThan,
+	"<=": code.OpLessEqual,
+	">=": code.OpGreaterEqual,
+	"in": code.OpIn,
+}
+
+func (c *Compiler) compileStatements(statements []ast.Statement) error {
+	for _, s := range statements {
+		if err := c.Compile(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compileBlock compiles block so that it leaves its value on the stack:
+// that of its last statement, or NULL if that is a let or there is none.
+func (c *Compiler) compileBlock(block *ast.BlockStatement) error {
+	if err := c.compileStatements(block.Statements); err != nil {
+		return err
+	}
+
+	if c.lastInstructionIs(code.OpPop) {
+		c.removeLastPop()
+	} else {
+		c.emit(code.OpNull)
+	}
+	return nil
+}
+
+func (c *Compiler) compileIfExpression(node *ast.IfExpression) error {
+	if err := c.Compile(node.Condition); err != nil {
+		return err
+	}
+
+	// Emit an `OpJumpNotTruthy` with a bogus value
+	jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+	if err := c.compileBlock(node.Consequence); err != nil {
+		return err
+	}
+
+	// Emit an `OpJump` with a bogus value
+	jumpPos := c.emit(code.OpJump, 9999)
+
+	afterConsequencePos := len(c.currentInstructions())
+	c.changeOperand(jumpNotTruthyPos, afterConsequencePos)
+
+	if node.Alternative == nil {
+		c.emit(code.OpNull)
+	} else if err := c.compileBlock(node.Alternative); err != nil {
+		return err
+	}
+
+	afterAlternativePos := len(c.currentInstructions())
+	c.changeOperand(jumpPos, afterAlternativePos)
+
+	return nil
+}
+
+func (c *Compiler) compileCallExpression(node *ast.CallExpression) error {
+	if len(node.Arguments) > 255 {
+		return fmt.Errorf("too many arguments in call to %s", node.Function)
+	}
+
+	dot, isMethod := node.Function.(*ast.DotExpression)
+	if isMethod {
+		if err := c.Compile(dot.Left); err != nil {
+			return err
+		}
+	} else if err := c.Compile(node.Function); err != nil {
+		return err
+	}
+
+	for _, a := range node.Arguments {
+		if err := c.Compile(a); err != nil {
+			return err
+		}
+	}
+
+	if !isMethod {
+		c.emit(code.OpCall, len(node.Arguments))
+		return nil
+	}
+
+	name, err := c.addConstant(&object.String{Value: dot.Property.Value})
+	if err != nil {
+		return err
+	}
+	c.emit(code.OpCallMethod, name, len(node.Arguments))
+	return nil
+}
+
+// compileFunctions compiles the bodies of the function literals of the
+// current scope into their CompiledFunctions.
+func (c *Compiler) compileFunctions() error {
+	for _, f := range c.scopes[c.scopeIndex].funcs {
+		c.enterScope()
+
+		for _, p := range f.literal.Parameters {
+			c.symbolTable.Define(p.Value)
+		}
+		if err := c.compileStatements(f.literal.Body.Statements); err != nil {
+			return err
+		}
+		c.emitReturn()
+
+		if err := c.compileFunctions(); err != nil {
+			return err
+		}
+
+		symbolTable := c.symbolTable
+		if symbolTable.NumDefinitions() > 256 {
+			return fmt.Errorf("too many local variables in %s", f.literal)
+		}
+		if len(symbolTable.FreeSymbols) > 256 {
+			return fmt.Errorf("too many free variables in %s", f.literal)
+		}
+
+		f.fn.Instructions, f.fn.Cells = c.leaveScope()
+		f.fn.NumLocals = symbolTable.NumDefinitions()
+		f.fn.Locals = symbolTable.Names()
+		for _, s := range symbolTable.FreeSymbols {
+			f.fn.Free = append(f.fn.Free, object.FreeVariable{
+				Name:  s.Name,
+				Local: s.Scope == LocalScope,
+				Index: s.Index,
+			})
+		}
+	}
+
+	return nil
+}
+
+// emitReturn ends the program or a function body. Its value is that of
+// the last statement, unless that is a let or there is none.
+func (c *Compiler) emitReturn() {
+	if c.lastInstructionIs(code.OpPop) {
+		c.replaceLastPopWithReturn()
+	}
+	if !c.lastInstructionIs(code.OpReturnValue) {
+		c.emit(code.OpReturn)
+	}
+}
+
+func (c *Compiler) loadSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpGetGlobal, s.Index)
+	case LocalScope:
+		c.emitLocal(code.OpGetLocal, s.Index)
+	case BuiltinScope:
+		c.emit(code.OpGetBuiltin, s.Index)
+	case FreeScope:
+		c.emit(code.OpGetFree, s.Index)
+	}
+}
+
+func (c *Compiler) emitConstant(obj object.Object) error {
+	index, err := c.addConstant(obj)
+	if err != nil {
+		return err
+	}
+	c.emit(code.OpConstant, index)
+	return nil
+}
+
+func (c *Compiler) addConstant(obj object.Object) (int, error) {
+	if len(c.constants) > 65535 {
+		return 0, fmt.Errorf("too many constants")
+	}
+	c.constants = append(c.constants, obj)
+	return len(c.constants) - 1, nil
+}
+
+func (c *Compiler) emit(op code.Opcode, operands ...int) int {
+	ins := code.Make(op, operands...)
+	pos := c.addInstruction(ins)
+
+	c.setLastInstruction(op, pos)
+
+	return pos
+}
+
+func (c *Compiler) emitLocal(op code.Opcode, index int) int {
+	pos := c.emit(op, index)
+	scope := &c.scopes[c.scopeIndex]
+	scope.locals = append(scope.locals, pos)
+	return pos
+}
+
+func (c *Compiler) addInstruction(ins []byte) int {
+	posNewInstruction := len(c.currentInstructions())
+	c.scopes[c.scopeIndex].instructions = append(c.currentInstructions(), ins...)
+	return posNewInstruction
+}
+
+func (c *Compiler) setLastInstruction(op code.Opcode, pos int) {
+	previous := c.scopes[c.scopeIndex].lastInstruction
+	last := EmittedInstruction{Opcode: op, Position: pos}
+
+	c.scopes[c.scopeIndex].previousInstruction = previous
+	c.scopes[c.scopeIndex].lastInstruction = last
+}
+
+func (c *Compiler) lastInstructionIs(op code.Opcode) bool {
+	if len(c.currentInstructions()) == 0 {
+		return false
+	}
+
+	return c.scopes[c.scopeIndex].lastInstruction.Opcode == op
+}
+
+func (c *Compiler) removeLastPop() {
+	last := c.scopes[c.scopeIndex].lastInstruction
+	previous := c.scopes[c.scopeIndex].previousInstruction
+
+	old := c.currentInstructions()
+	new := old[:last.Position]
+
+	c.scopes[c.scopeIndex].instructions = new
+	c.scopes[c.scopeIndex].lastInstruction = previous
+}
+
+func (c *Compiler) replaceLastPopWithReturn() {
+	lastPos := c.scopes[c.scopeIndex].lastInstruction.Position
+	c.replaceInstruction(lastPos, code.Make(code.OpReturnValue))
+
+	c.scopes[c.scopeIndex].lastInstruction.Opcode = code.OpReturnValue
+}
+
+func (c *Compiler) replaceInstruction(pos int, newInstruction []byte) {
+	ins := c.currentInstructions()
+
+	for i := 0; i < len(newInstruction); i++ {
+		ins[pos+i] = newInstruction[i]
+	}
+}
+
+func (c *Compiler) changeOperand(opPos int, operand int) {
+	op := code.Opcode(c.currentInstructions()[opPos])
+	newInstruction := code.Make(op, operand)
+
+	c.replaceInstruction(opPos, newInstruction)
+}
+
+func (c *Compiler) currentInstructions() code.Instructions {
+	return c.scopes[c.scopeIndex].instructions
+}
+
+func (c *Compiler) enterScope() {
+	c.scopes = append(c.scopes, CompilationScope{})
+	c.scopeIndex++
+
+	c.symbolTable = NewEnclosedSymbolTable(c.symbolTable)
+}
+
+// leaveScope returns the instructions of the scope it leaves, with the
+// locals that functions inside capture turned into cells, and those
+// locals' indexes.
+func (c *Compiler) leaveScope() (code.Instructions, []int) {
+	scope := c.scopes[c.scopeIndex]
+	instructions := scope.instructions
+
+	var cells []int
+	for i := 0; i < c.symbolTable.NumDefinitions(); i++ {
+		if c.symbolTable.Captured(i) {
+			cells = append(cells, i)
+		}
+	}
+	for _, pos := range scope.locals {
+		if !c.symbolTable.Captured(int(instructions[pos+1])) {
+			continue
+		}
+		if code.Opcode(instructions[pos]) == code.OpGetLocal {
+			instructions[pos] = byte(code.OpGetCell)
+		} else {
+			instructions[pos] = byte(code.OpSetCell)
+		}
+	}
+
+	c.scopes = c.scopes[:len(c.scopes)-1]
+	c.scopeIndex--
+
+	c.symbolTable = c.symbolTable.Outer
+
+	return instructions, cells
+}
+
+func (c *Compiler) Bytecode() *Bytecode {
+	return &Bytecode{
+		Instructions: c.currentInstructions(),
+		Constants:    c.constants,
+		Globals:      c.symbolTable.Names(),
+		Builtins:     c.builtins,
+	}
+}
@@ -0,0 +1,125 @@
+package compiler
+
+type SymbolScope string
+
+const (
+	GlobalScope  SymbolScope = "GLOBAL"
+	LocalScope   SymbolScope = "LOCAL"
+	BuiltinScope SymbolScope = "BUILTIN"
+	FreeScope    SymbolScope = "FREE"
+)
+
+type Symbol struct {
+	Name  string
+	Scope SymbolScope
+	Index int
+}
+
+// A SymbolTable holds the names of the program or of one function, as
+// object.NewEnclosedEnvironment does at run time. Blocks don't get their
+// own table, since if and else run in the environment around them.
+type SymbolTable struct {
+	Outer *SymbolTable
+
+	store map[string]Symbol
+	// names are the names of the globals or locals s defines, by index.
+	names []string
+
+	// FreeSymbols are the symbols of the tables around this one that its
+	// function uses, in the order of their FreeScope indexes.
+	FreeSymbols []Symbol
+
+	// captured holds the indexes of the locals that functions inside this
+	// one use as free variables.
+	captured map[int]bool
+
+	// builtins are the builtins by name. Only the global table has them.
+	builtins map[string]int
+}
+
+func NewSymbolTable() *SymbolTable {
+	return &SymbolTable{
+		store:    make(map[string]Symbol),
+		captured: make(map[int]bool),
+		builtins: make(map[string]int),
+	}
+}
+
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewSymbolTable()
+	s.Outer = outer
+	return s
+}
+
+// Define binds name in s. A second let of a name in the same table
+// replaces its value at run time, so it gets the same symbol.
+func (s *SymbolTable) Define(name string) Symbol {
+	scope := GlobalScope
+	if s.Outer != nil {
+		scope = LocalScope
+	}
+
+	if symbol, ok := s.store[name]; ok && symbol.Scope == scope {
+		return symbol
+	}
+
+	symbol := Symbol{Name: name, Scope: scope, Index: len(s.names)}
+	s.store[name] = symbol
+	s.names = append(s.names, name)
+	return symbol
+}
+
+func (s *SymbolTable) DefineBuiltin(index int, name string) Symbol {
+	s.builtins[name] = index
+	return Symbol{Name: name, Scope: BuiltinScope, Index: index}
+}
+
+// Resolve finds the symbol name refers to. Names of the tables around a
+// function become its free variables, unless they are globals or builtins.
+// A name found nowhere is taken to be a global whose let hasn't been
+// compiled, or never runs; reading it before it is set is an error at run
+// time, as it is for the evaluator.
+func (s *SymbolTable) Resolve(name string) Symbol {
+	if symbol, ok := s.store[name]; ok {
+		return symbol
+	}
+
+	if s.Outer == nil {
+		if index, ok := s.builtins[name]; ok {
+			return Symbol{Name: name, Scope: BuiltinScope, Index: index}
+		}
+		return s.Define(name)
+	}
+
+	symbol := s.Outer.Resolve(name)
+	if symbol.Scope == GlobalScope || symbol.Scope == BuiltinScope {
+		return symbol
+	}
+	if symbol.Scope == LocalScope {
+		s.Outer.captured[symbol.Index] = true
+	}
+	return s.defineFree(symbol)
+}
+
+func (s *SymbolTable) defineFree(original Symbol) Symbol {
+	s.FreeSymbols = append(s.FreeSymbols, original)
+
+	symbol := Symbol{Name: original.Name, Scope: FreeScope, Index: len(s.FreeSymbols) - 1}
+	s.store[original.Name] = symbol
+	return symbol
+}
+
+// Captured reports whether a function inside s uses the local at index.
+func (s *SymbolTable) Captured(index int) bool {
+	return s.captured[index]
+}
+
+// NumDefinitions is the number of globals or locals s defines.
+func (s *SymbolTable) NumDefinitions() int {
+	return len(s.names)
+}
+
+// Names returns the names of the globals or locals s defines, by index.
+func (s *SymbolTable) Names() []string {
+	return s.names
+}
@@ -0,0 +1,92 @@
+package compiler
+
+import "testing"
+
+func TestDefine(t *testing.T) {
+	global := NewSymbolTable()
+	if a := global.Define("a"); a != (Symbol{Name: "a", Scope: GlobalScope, Index: 0}) {
+		t.Errorf("a=%+v", a)
+	}
+	if b := global.Define("b"); b != (Symbol{Name: "b", Scope: GlobalScope, Index: 1}) {
+		t.Errorf("b=%+v", b)
+	}
+	// A second let of a name is the same variable.
+	if a := global.Define("a"); a.Index != 0 {
+		t.Errorf("a was defined again at %d", a.Index)
+	}
+
+	local := NewEnclosedSymbolTable(global)
+	if a := local.Define("a"); a != (Symbol{Name: "a", Scope: LocalScope, Index: 0}) {
+		t.Errorf("local a=%+v", a)
+	}
+	if got := local.NumDefinitions(); got != 1 {
+		t.Errorf("local has %d definitions, want 1", got)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineBuiltin(0, "len")
+	global.Define("a")
+
+	first := NewEnclosedSymbolTable(global)
+	first.Define("b")
+	first.Define("c")
+
+	second := NewEnclosedSymbolTable(first)
+	second.Define("d")
+
+	tests := []struct {
+		table    *SymbolTable
+		name     string
+		expected Symbol
+	}{
+		{second, "a", Symbol{Name: "a", Scope: GlobalScope, Index: 0}},
+		{second, "len", Symbol{Name: "len", Scope: BuiltinScope, Index: 0}},
+		{second, "d", Symbol{Name: "d", Scope: LocalScope, Index: 0}},
+		{second, "c", Symbol{Name: "c", Scope: FreeScope, Index: 0}},
+		{second, "b", Symbol{Name: "b", Scope: FreeScope, Index: 1}},
+		{second, "c", Symbol{Name: "c", Scope: FreeScope, Index: 0}},
+		// Names found nowhere are globals yet to be set.
+		{second, "e", Symbol{Name: "e", Scope: GlobalScope, Index: 1}},
+	}
+
+	for _, tt := range tests {
+		if got := tt.table.Resolve(tt.name); got != tt.expected {
+			t.Errorf("%s resolved to %+v, want %+v", tt.name, got, tt.expected)
+		}
+	}
+
+	if len(second.FreeSymbols) != 2 {
+		t.Fatalf("second has %d free symbols, want 2", len(second.FreeSymbols))
+	}
+	if !first.Captured(0) || !first.Captured(1) {
+		t.Errorf("b and c of first are not captured")
+	}
+
+	// A let after a name was used from outside makes a local.
+	if c := second.Define("c"); c != (Symbol{Name: "c", Scope: LocalScope, Index: 1}) {
+		t.Errorf("c=%+v", c)
+	}
+}
+
+func TestResolveFreeOfFree(t *testing.T) {
+	global := NewSymbolTable()
+	first := NewEnclosedSymbolTable(global)
+	first.Define("a")
+	second := NewEnclosedSymbolTable(first)
+	third := NewEnclosedSymbolTable(second)
+
+	if got := third.Resolve("a"); got != (Symbol{Name: "a", Scope: FreeScope, Index: 0}) {
+		t.Errorf("third resolved a to %+v", got)
+	}
+	if len(second.FreeSymbols) != 1 || second.FreeSymbols[0].Scope != LocalScope {
+		t.Errorf("second has free symbols %+v", second.FreeSymbols)
+	}
+	if len(third.FreeSymbols) != 1 || third.FreeSymbols[0].Scope != FreeScope {
+		t.Errorf("third has free symbols %+v", third.FreeSymbols)
+	}
+	if !first.Captured(0) {
+		t.Errorf("a of first is not captured")
+	}
+}
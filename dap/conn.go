@@ -0,0 +1,87 @@
+package dap
+
+import (
+	"cathon/wire"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// conn reads and writes messages framed by a Content-Length header, as
+// the base protocol of DAP does, numbering those it writes.
+type conn struct {
+	r *wire.Reader
+
+	mu  sync.Mutex
+	w   io.Writer
+	seq int
+}
+
+func newConn(r io.Reader, w io.Writer) *conn {
+	return &conn{r: wire.NewReader(r), w: w}
+}
+
+func (c *conn) read() (*message, error) {
+	body, err := c.r.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("dap: %w", err)
+	}
+
+	msg := &message{}
+	if err := json.Unmarshal(body, msg); err != nil {
+		return nil, fmt.Errorf("dap: bad message: %w", err)
+	}
+	return msg, nil
+}
+
+// write numbers the message build returns and writes it.
+func (c *conn) write(build func(seq int) interface{}) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.seq++
+	body, err := json.Marshal(build(c.seq))
+	if err != nil {
+		return 0, err
+	}
+	return c.seq, wire.Write(c.w, body)
+}
+
+// request sends a request and returns its seq.
+func (c *conn) request(command string, args interface{}) (int, error) {
+	return c.write(func(seq int) interface{} {
+		return &request{Seq: seq, Type: "request", Command: command, Arguments: args}
+	})
+}
+
+// respond answers req with body, or with err if it isn't nil.
+func (c *conn) respond(req *message, body interface{}, err error) error {
+	_, werr := c.write(func(seq int) interface{} {
+		resp := &response{
+			Seq:        seq,
+			Type:       "response",
+			RequestSeq: req.Seq,
+			Command:    req.Command,
+			Success:    err == nil,
+			Body:       body,
+		}
+		if err != nil {
+			resp.Message = err.Error()
+			resp.Body = nil
+		}
+		return resp
+	})
+	return werr
+}
+
+// event sends the event name with body.
+func (c *conn) event(name string, body interface{}) error {
+	_, err := c.write(func(seq int) interface{} {
+		return &event{Seq: seq, Type: "event", Event: name, Body: body}
+	})
+	return err
+}
@@ -0,0 +1,653 @@
+// Package dap implements a Debug Adapter Protocol server for cathon
+// scripts, so editors such as VS Code can run them with breakpoints,
+// stepping, the call stack and variables. The script runs on its own
+// goroutine, which the evaluator's Hook pauses while it is stopped.
+package dap
+
+import (
+	"cathon/ast"
+	"cathon/evaluator"
+	"cathon/lexer"
+	"cathon/object"
+	"cathon/parser"
+	"cathon/pretty"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// threadID is the only thread, the one running the script.
+const threadID = 1
+
+// errTerminated unwinds the script when the client ends it.
+var errTerminated = errors.New("dap: terminated")
+
+var errNotStopped = errors.New("the script is not stopped")
+
+// mode is what a running script runs until.
+type mode int
+
+const (
+	modeContinue mode = iota // a breakpoint
+	modeEntry                // the first statement
+	modeStep                 // any new line
+	modeNext                 // a new line at the same depth or above
+	modeOut                  // a new line above the current depth
+	modeQuit                 // the script ends now
+)
+
+// frame is a function call in progress, or the program itself.
+type frame struct {
+	name         string
+	env          *object.Environment
+	builtin      bool
+	line, column int
+}
+
+// valueOptions keep values on one line, as editors show them.
+var valueOptions = pretty.Options{Width: math.MaxInt32, MaxItems: 20}
+
+// Server serves one debugging session over a pair of streams, usually
+// stdin and stdout or a network connection.
+type Server struct {
+	conn *conn
+	// then runs after the response to the current request is written, so
+	// events from the script never come before it.
+	then func()
+
+	launch     *LaunchArguments
+	program    *ast.Program
+	configured bool
+	started    bool
+	interp     *evaluator.Interpreter
+	resume     chan mode
+	done       chan struct{}
+
+	// mu guards the fields below, which the script's goroutine uses too.
+	mu          sync.Mutex
+	breakpoints map[int]bool
+	frames      []*frame
+	stopped     bool
+	mode        mode
+	depth       int // of the frame the step started in
+	terminating bool
+	evaluating  bool
+
+	// lastLine and lastDepth are where the previous statement was, so a
+	// line with several statements stops only once.
+	lastLine, lastDepth int
+
+	// refs are the scopes and values the client can expand, by
+	// variablesReference less one. They are dropped when the script
+	// resumes.
+	refs []interface{}
+}
+
+// Serve reads requests from in and writes responses and events to out
+// until the client disconnects or closes in.
+func (srv *Server) Serve(in io.Reader, out io.Writer) error {
+	srv.conn = newConn(in, out)
+	srv.breakpoints = make(map[int]bool)
+	srv.resume = make(chan mode)
+	srv.done = make(chan struct{})
+
+	for {
+		msg, err := srv.conn.read()
+		if err == io.EOF {
+			srv.terminate()
+			return nil
+		}
+		if err != nil {
+			srv.terminate()
+			return err
+		}
+		if msg.Type != "request" {
+			continue
+		}
+
+		handler, ok := requests[msg.Command]
+		if !ok {
+			err = srv.conn.respond(msg, nil, fmt.Errorf("unsupported command %s", msg.Command))
+		} else {
+			body, herr := handler(srv, msg.Arguments)
+			err = srv.conn.respond(msg, body, herr)
+		}
+		if then := srv.then; then != nil {
+			srv.then = nil
+			then()
+		}
+		if err != nil {
+			srv.terminate()
+			return err
+		}
+		if msg.Command == "disconnect" {
+			return nil
+		}
+	}
+}
+
+// ServeConns accepts connections on l and serves a session on each, in its
+// own goroutine, until l is closed. It then returns nil.
+func ServeConns(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		go func() {
+			defer conn.Close()
+			srv := &Server{}
+			srv.Serve(conn, conn)
+		}()
+	}
+}
+
+type handler func(srv *Server, args json.RawMessage) (interface{}, error)
+
+var requests = map[string]handler{
+	"initialize":        (*Server).initialize,
+	"launch":            (*Server).launchRequest,
+	"setBreakpoints":    (*Server).setBreakpoints,
+	"configurationDone": (*Server).configurationDone,
+	"threads":           (*Server).threads,
+	"stackTrace":        (*Server).stackTrace,
+	"scopes":            (*Server).scopes,
+	"variables":         (*Server).variables,
+	"evaluate":          (*Server).evaluate,
+	"continue":          (*Server).continueRequest,
+	"next":              (*Server).next,
+	"stepIn":            (*Server).stepIn,
+	"stepOut":           (*Server).stepOut,
+	"terminate":         (*Server).terminateRequest,
+	"disconnect":        (*Server).disconnect,
+}
+
+func decode(args json.RawMessage, v interface{}) error {
+	if len(args) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(args, v); err != nil {
+		return fmt.Errorf("bad arguments: %s", err)
+	}
+	return nil
+}
+
+func (srv *Server) initialize(args json.RawMessage) (interface{}, error) {
+	srv.then = func() { srv.conn.event("initialized", nil) }
+	return Capabilities{
+		SupportsConfigurationDoneRequest: true,
+		SupportsEvaluateForHovers:        true,
+		SupportsTerminateRequest:         true,
+	}, nil
+}
+
+func (srv *Server) launchRequest(args json.RawMessage) (interface{}, error) {
+	if srv.launch != nil {
+		return nil, errors.New("already launched")
+	}
+	var a LaunchArguments
+	if err := decode(args, &a); err != nil {
+		return nil, err
+	}
+
+	src, err := os.ReadFile(a.Program)
+	if err != nil {
+		return nil, err
+	}
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("parser errors:\n\t%s", strings.Join(p.Errors(), "\n\t"))
+	}
+
+	srv.launch = &a
+	srv.program = program
+	if srv.configured {
+		srv.then = srv.start
+	}
+	return nil, nil
+}
+
+func (srv *Server) configurationDone(args json.RawMessage) (interface{}, error) {
+	srv.configured = true
+	if srv.launch != nil && !srv.started {
+		srv.then = srv.start
+	}
+	return nil, nil
+}
+
+// start runs the script on its own goroutine, which reports its output and
+// its end as events.
+func (srv *Server) start() {
+	srv.started = true
+
+	elements := make([]object.Object, len(srv.launch.Args))
+	for i, arg := range srv.launch.Args {
+		elements[i] = &object.String{Value: arg}
+	}
+	env := object.NewEnvironment()
+	env.Set("args", &object.Array{Elements: elements})
+
+	srv.interp = evaluator.New()
+	srv.interp.Out = &output{conn: srv.conn, category: "stdout"}
+	srv.mu.Lock()
+	srv.frames = []*frame{{name: "<main>", env: env}}
+	if srv.launch.StopOnEntry {
+		srv.mode = modeEntry
+	}
+	srv.mu.Unlock()
+	srv.interp.Hook = srv
+
+	go func() {
+		defer close(srv.done)
+
+		result, ok := srv.run(env)
+		if ok {
+			status, err := evaluator.ExitStatus(result)
+			if err != nil {
+				srv.conn.event("output", OutputEvent{Category: "stderr", Output: err.Inspect() + "\n"})
+			}
+			srv.conn.event("exited", ExitedEvent{ExitCode: status})
+		}
+		srv.conn.event("terminated", nil)
+	}()
+}
+
+// run evaluates the program, reporting false if the client ended it.
+func (srv *Server) run(env *object.Environment) (result object.Object, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if r != errTerminated {
+				panic(r)
+			}
+			result, ok = nil, false
+		}
+	}()
+	return srv.interp.Eval(srv.program, env), true
+}
+
+// output sends what the script writes as output events.
+type output struct {
+	conn     *conn
+	category string
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	if err := o.conn.event("output", OutputEvent{Category: o.category, Output: string(p)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (srv *Server) setBreakpoints(args json.RawMessage) (interface{}, error) {
+	var a SetBreakpointsArguments
+	if err := decode(args, &a); err != nil {
+		return nil, err
+	}
+
+	// Scripts are one file, so the breakpoints of any source are the
+	// script's.
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.breakpoints = make(map[int]bool)
+	breakpoints := []Breakpoint{}
+	for _, bp := range a.Breakpoints {
+		srv.breakpoints[bp.Line] = true
+		breakpoints = append(breakpoints, Breakpoint{Verified: true, Line: bp.Line})
+	}
+	return map[string]interface{}{"breakpoints": breakpoints}, nil
+}
+
+func (srv *Server) threads(args json.RawMessage) (interface{}, error) {
+	return map[string]interface{}{
+		"threads": []Thread{{ID: threadID, Name: "main"}},
+	}, nil
+}
+
+func (srv *Server) stackTrace(args json.RawMessage) (interface{}, error) {
+	var a StackTraceArguments
+	if err := decode(args, &a); err != nil {
+		return nil, err
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if !srv.stopped {
+		return nil, errNotStopped
+	}
+
+	source := &Source{Name: filepath.Base(srv.launch.Program), Path: srv.launch.Program}
+	frames := []StackFrame{}
+	for i := len(srv.frames) - 1; i >= 0; i-- {
+		f := srv.frames[i]
+		frames = append(frames, StackFrame{
+			ID:     i + 1,
+			Name:   f.name,
+			Source: source,
+			Line:   f.line,
+			Column: f.column,
+		})
+	}
+
+	total := len(frames)
+	frames = frames[min(a.StartFrame, total):]
+	if a.Levels > 0 && a.Levels < len(frames) {
+		frames = frames[:a.Levels]
+	}
+	return map[string]interface{}{"stackFrames": frames, "totalFrames": total}, nil
+}
+
+// frame returns the frame with id, or the top one for 0. srv.mu is held.
+func (srv *Server) frame(id int) (*frame, error) {
+	if id == 0 {
+		return srv.frames[len(srv.frames)-1], nil
+	}
+	if id < 1 || id > len(srv.frames) {
+		return nil, fmt.Errorf("no frame %d", id)
+	}
+	return srv.frames[id-1], nil
+}
+
+func (srv *Server) scopes(args json.RawMessage) (interface{}, error) {
+	var a ScopesArguments
+	if err := decode(args, &a); err != nil {
+		return nil, err
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if !srv.stopped {
+		return nil, errNotStopped
+	}
+	f, err := srv.frame(a.FrameID)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := []Scope{}
+	if !f.builtin {
+		scopes = append(scopes, Scope{Name: "Locals", VariablesReference: srv.ref(f.env)})
+	}
+	if global := srv.frames[0]; f != global {
+		scopes = append(scopes, Scope{Name: "Globals", VariablesReference: srv.ref(global.env)})
+	}
+	return map[string]interface{}{"scopes": scopes}, nil
+}
+
+// ref returns a variablesReference for v. srv.mu is held.
+func (srv *Server) ref(v interface{}) int {
+	srv.refs = append(srv.refs, v)
+	return len(srv.refs)
+}
+
+func (srv *Server) variables(args json.RawMessage) (interface{}, error) {
+	var a VariablesArguments
+	if err := decode(args, &a); err != nil {
+		return nil, err
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if !srv.stopped {
+		return nil, errNotStopped
+	}
+	if a.VariablesReference < 1 || a.VariablesReference > len(srv.refs) {
+		return nil, fmt.Errorf("no variables %d", a.VariablesReference)
+	}
+
+	variables := []Variable{}
+	switch v := srv.refs[a.VariablesReference-1].(type) {
+	case *object.Environment:
+		for _, name := range v.LocalNames() {
+			value, _ := v.Get(name)
+			variables = append(variables, srv.variable(name, value))
+		}
+	case *object.Array:
+		for i, element := range v.Elements {
+			variables = append(variables, srv.variable(fmt.Sprintf("[%d]", i), element))
+		}
+	case *object.Hash:
+		for _, pair := range v.Pairs() {
+			variables = append(variables, srv.variable(pretty.Sprint(pair.Key, valueOptions), pair.Value))
+		}
+	case *object.Module:
+		names := make([]string, 0, len(v.Members))
+		for name := range v.Members {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			variables = append(variables, srv.variable(name, v.Members[name]))
+		}
+	}
+	return map[string]interface{}{"variables": variables}, nil
+}
+
+// variable describes value, giving it a reference if it has elements to
+// expand. srv.mu is held.
+func (srv *Server) variable(name string, value object.Object) Variable {
+	return Variable{
+		Name:               name,
+		Value:              pretty.Sprint(value, valueOptions),
+		Type:               string(value.Type()),
+		VariablesReference: srv.children(value),
+	}
+}
+
+// children returns a reference for the elements of value, or 0 if it has
+// none. srv.mu is held.
+func (srv *Server) children(value object.Object) int {
+	switch value := value.(type) {
+	case *object.Array:
+		if len(value.Elements) > 0 {
+			return srv.ref(value)
+		}
+	case *object.Hash:
+		if value.Len() > 0 {
+			return srv.ref(value)
+		}
+	case *object.Module:
+		return srv.ref(value)
+	}
+	return 0
+}
+
+// evaluate runs an expression in a frame of the stopped script. The script
+// doesn't stop at breakpoints in functions the expression calls.
+func (srv *Server) evaluate(args json.RawMessage) (interface{}, error) {
+	var a EvaluateArguments
+	if err := decode(args, &a); err != nil {
+		return nil, err
+	}
+
+	srv.mu.Lock()
+	if !srv.stopped {
+		srv.mu.Unlock()
+		return nil, errNotStopped
+	}
+	f, err := srv.frame(a.FrameID)
+	if err != nil {
+		srv.mu.Unlock()
+		return nil, err
+	}
+	env := f.env
+	srv.evaluating = true
+	srv.mu.Unlock()
+
+	p := parser.New(lexer.New(a.Expression))
+	program := p.ParseProgram()
+	var result object.Object
+	if len(p.Errors()) == 0 {
+		// The script's goroutine is waiting on resume, so the interpreter
+		// is free.
+		result = srv.interp.Eval(program, env)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.evaluating = false
+
+	if len(p.Errors()) != 0 {
+		return nil, errors.New(strings.Join(p.Errors(), "\n"))
+	}
+	if result == nil {
+		result = object.NULL
+	}
+	if err, ok := result.(*object.Error); ok {
+		return nil, errors.New(err.Message)
+	}
+	return EvaluateResponse{
+		Result:             pretty.Sprint(result, valueOptions),
+		Type:               string(result.Type()),
+		VariablesReference: srv.children(result),
+	}, nil
+}
+
+func (srv *Server) continueRequest(args json.RawMessage) (interface{}, error) {
+	if err := srv.resumeWith(modeContinue); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"allThreadsContinued": true}, nil
+}
+
+func (srv *Server) next(args json.RawMessage) (interface{}, error) {
+	return nil, srv.resumeWith(modeNext)
+}
+
+func (srv *Server) stepIn(args json.RawMessage) (interface{}, error) {
+	return nil, srv.resumeWith(modeStep)
+}
+
+func (srv *Server) stepOut(args json.RawMessage) (interface{}, error) {
+	return nil, srv.resumeWith(modeOut)
+}
+
+// resumeWith lets the stopped script run until m says to stop, once the
+// response is written.
+func (srv *Server) resumeWith(m mode) error {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if !srv.stopped {
+		return errNotStopped
+	}
+
+	srv.stopped = false
+	srv.refs = nil
+	srv.mode = m
+	srv.depth = len(srv.frames) - 1
+	srv.then = func() { srv.resume <- m }
+	return nil
+}
+
+func (srv *Server) terminateRequest(args json.RawMessage) (interface{}, error) {
+	srv.then = srv.terminate
+	return nil, nil
+}
+
+func (srv *Server) disconnect(args json.RawMessage) (interface{}, error) {
+	srv.then = srv.terminate
+	return nil, nil
+}
+
+// terminate ends the script, if it is running, and waits for it.
+func (srv *Server) terminate() {
+	if !srv.started {
+		return
+	}
+
+	srv.mu.Lock()
+	srv.terminating = true
+	stopped := srv.stopped
+	srv.stopped = false
+	srv.mu.Unlock()
+
+	if stopped {
+		srv.resume <- modeQuit
+	}
+	<-srv.done
+}
+
+// Statement stops the script before stmt when a breakpoint or a step says
+// so, and waits for the client to resume it.
+func (srv *Server) Statement(stmt ast.Statement, pos evaluator.Position, env *object.Environment) {
+	srv.mu.Lock()
+	if srv.evaluating {
+		srv.mu.Unlock()
+		return
+	}
+	if srv.terminating {
+		srv.mu.Unlock()
+		panic(errTerminated)
+	}
+
+	depth := len(srv.frames) - 1
+	f := srv.frames[depth]
+	f.line, f.column = pos.Line, pos.Column
+	newLine := pos.Line != srv.lastLine || depth != srv.lastDepth
+	srv.lastLine, srv.lastDepth = pos.Line, depth
+
+	var reason string
+	switch {
+	case !newLine, srv.launch.NoDebug:
+	case srv.breakpoints[pos.Line]:
+		reason = "breakpoint"
+	case srv.mode == modeEntry:
+		reason = "entry"
+	case srv.mode == modeStep,
+		srv.mode == modeNext && depth <= srv.depth,
+		srv.mode == modeOut && depth < srv.depth:
+		reason = "step"
+	}
+	if reason == "" {
+		srv.mu.Unlock()
+		return
+	}
+	srv.stopped = true
+	srv.mu.Unlock()
+
+	srv.conn.event("stopped", StoppedEvent{Reason: reason, ThreadID: threadID, AllThreadsStopped: true})
+	if <-srv.resume == modeQuit {
+		panic(errTerminated)
+	}
+}
+
+// Call pushes a frame for the function.
+func (srv *Server) Call(call *ast.CallExpression, fn object.Object, pos evaluator.Position, env *object.Environment) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.evaluating {
+		return
+	}
+
+	name := evaluator.CallName(call)
+	_, builtin := fn.(*object.Builtin)
+	srv.frames = append(srv.frames, &frame{
+		name:    name,
+		env:     env,
+		builtin: builtin,
+		line:    pos.Line,
+		column:  pos.Column,
+	})
+}
+
+// Return pops the frame of the function.
+func (srv *Server) Return(fn object.Object, result object.Object) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.evaluating {
+		return
+	}
+	srv.frames = srv.frames[:len(srv.frames)-1]
+}
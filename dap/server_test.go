@@ -0,0 +1,443 @@
+package dap
+
+import (
+	"cathon/wire"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const script = `let double = fn(x) {
+  let y = x * 2;
+  y
+};
+let a = double(3);
+puts(a);
+let b = double(a);
+b
+`
+
+// testClient drives a Server in the same process, as an editor would.
+type testClient struct {
+	t        *testing.T
+	conn     *conn
+	messages chan *message
+	done     chan error
+
+	// events holds events read while waiting for something else.
+	events []*message
+}
+
+func startServer(t *testing.T) *testClient {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	return newTestClient(t, newConn(outR, inW), func() error {
+		err := (&Server{}).Serve(inR, outW)
+		outW.Close()
+		return err
+	}, func() { inW.Close() })
+}
+
+func newTestClient(t *testing.T, c *conn, serve func() error, cleanup func()) *testClient {
+	client := &testClient{t: t, conn: c, messages: make(chan *message, 100), done: make(chan error, 1)}
+	if serve != nil {
+		go func() { client.done <- serve() }()
+	}
+	go func() {
+		defer close(client.messages)
+		for {
+			msg, err := c.read()
+			if err != nil {
+				return
+			}
+			client.messages <- msg
+		}
+	}()
+	t.Cleanup(cleanup)
+	return client
+}
+
+func (c *testClient) read() *message {
+	c.t.Helper()
+	select {
+	case msg, ok := <-c.messages:
+		if !ok {
+			c.t.Fatal("the server closed the connection")
+		}
+		return msg
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("timed out waiting for the server")
+		return nil
+	}
+}
+
+// request sends a request and decodes the body of a successful response
+// into body. It returns the response.
+func (c *testClient) request(command string, args, body interface{}) *message {
+	c.t.Helper()
+	seq, err := c.conn.request(command, args)
+	if err != nil {
+		c.t.Fatalf("writing %s failed: %s", command, err)
+	}
+
+	for {
+		msg := c.read()
+		if msg.Type == "event" {
+			c.events = append(c.events, msg)
+			continue
+		}
+		if msg.Type != "response" || msg.RequestSeq != seq || msg.Command != command {
+			c.t.Fatalf("unexpected reply to %s: %+v", command, msg)
+		}
+		if msg.Success && body != nil {
+			if err := json.Unmarshal(msg.Body, body); err != nil {
+				c.t.Fatalf("bad body for %s: %s", command, err)
+			}
+		}
+		return msg
+	}
+}
+
+// mustRequest is request for requests that must succeed.
+func (c *testClient) mustRequest(command string, args, body interface{}) {
+	c.t.Helper()
+	if resp := c.request(command, args, body); !resp.Success {
+		c.t.Fatalf("%s failed: %s", command, resp.Message)
+	}
+}
+
+// event waits for the event name, skipping the ones before it, and decodes
+// its body into body.
+func (c *testClient) event(name string, body interface{}) {
+	c.t.Helper()
+	for {
+		var msg *message
+		if len(c.events) > 0 {
+			msg, c.events = c.events[0], c.events[1:]
+		} else {
+			msg = c.read()
+		}
+		if msg.Type != "event" {
+			c.t.Fatalf("unexpected %s while waiting for %s", msg.Type, name)
+		}
+		if msg.Event != name {
+			continue
+		}
+		if body != nil {
+			if err := json.Unmarshal(msg.Body, body); err != nil {
+				c.t.Fatalf("bad body for %s: %s", name, err)
+			}
+		}
+		return
+	}
+}
+
+// stopped waits for the script to stop and checks why.
+func (c *testClient) stopped(reason string) {
+	c.t.Helper()
+	var stopped StoppedEvent
+	c.event("stopped", &stopped)
+	if stopped.Reason != reason {
+		c.t.Fatalf("wrong reason. expected=%q, got=%q", reason, stopped.Reason)
+	}
+}
+
+// stack returns the names and lines of the stack, innermost first.
+func (c *testClient) stack() ([]string, []int) {
+	c.t.Helper()
+	var trace struct{ StackFrames []StackFrame }
+	c.mustRequest("stackTrace", StackTraceArguments{ThreadID: threadID}, &trace)
+
+	names, lines := []string{}, []int{}
+	for _, f := range trace.StackFrames {
+		names = append(names, f.Name)
+		lines = append(lines, f.Line)
+	}
+	return names, lines
+}
+
+func (c *testClient) expectStack(names []string, lines []int) {
+	c.t.Helper()
+	gotNames, gotLines := c.stack()
+	if !reflect.DeepEqual(gotNames, names) || !reflect.DeepEqual(gotLines, lines) {
+		c.t.Fatalf("wrong stack. expected=%v %v, got=%v %v", names, lines, gotNames, gotLines)
+	}
+}
+
+func (c *testClient) variables(ref int) map[string]Variable {
+	c.t.Helper()
+	var body struct{ Variables []Variable }
+	c.mustRequest("variables", VariablesArguments{VariablesReference: ref}, &body)
+
+	vars := make(map[string]Variable)
+	for _, v := range body.Variables {
+		vars[v.Name] = v
+	}
+	return vars
+}
+
+// launch starts a session debugging src with breakpoints at lines.
+func (c *testClient) launch(src string, args LaunchArguments, lines ...int) {
+	c.t.Helper()
+	path := filepath.Join(c.t.TempDir(), "script.cth")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		c.t.Fatal(err)
+	}
+	args.Program = path
+
+	var caps Capabilities
+	c.mustRequest("initialize", map[string]string{"adapterID": "cathon"}, &caps)
+	if !caps.SupportsConfigurationDoneRequest {
+		c.t.Fatalf("configurationDone not supported: %+v", caps)
+	}
+	c.event("initialized", nil)
+
+	c.mustRequest("launch", args, nil)
+	breakpoints := []SourceBreakpoint{}
+	for _, line := range lines {
+		breakpoints = append(breakpoints, SourceBreakpoint{Line: line})
+	}
+	c.mustRequest("setBreakpoints", SetBreakpointsArguments{
+		Source: Source{Path: path}, Breakpoints: breakpoints}, nil)
+	c.mustRequest("configurationDone", nil, nil)
+}
+
+// finish waits for the script to exit and checks its exit code.
+func (c *testClient) finish(code int) {
+	c.t.Helper()
+	var exited ExitedEvent
+	c.event("exited", &exited)
+	if exited.ExitCode != code {
+		c.t.Errorf("wrong exit code. expected=%d, got=%d", code, exited.ExitCode)
+	}
+	c.event("terminated", nil)
+}
+
+func TestBreakpointsAndVariables(t *testing.T) {
+	c := startServer(t)
+	c.launch(script, LaunchArguments{}, 3)
+
+	c.stopped("breakpoint")
+	var threads struct{ Threads []Thread }
+	c.mustRequest("threads", nil, &threads)
+	if len(threads.Threads) != 1 || threads.Threads[0].ID != threadID {
+		t.Fatalf("wrong threads: %+v", threads.Threads)
+	}
+	c.expectStack([]string{"double", "<main>"}, []int{3, 5})
+
+	var scopes struct{ Scopes []Scope }
+	c.mustRequest("scopes", ScopesArguments{FrameID: 2}, &scopes)
+	if len(scopes.Scopes) != 2 || scopes.Scopes[0].Name != "Locals" || scopes.Scopes[1].Name != "Globals" {
+		t.Fatalf("wrong scopes: %+v", scopes.Scopes)
+	}
+	locals := c.variables(scopes.Scopes[0].VariablesReference)
+	if len(locals) != 2 || locals["x"].Value != "3" || locals["y"].Value != "6" || locals["y"].Type != "INTEGER" {
+		t.Errorf("wrong locals: %+v", locals)
+	}
+	globals := c.variables(scopes.Scopes[1].VariablesReference)
+	if globals["double"].Value != "fn(x) {...}" || globals["args"].Value != "[]" {
+		t.Errorf("wrong globals: %+v", globals)
+	}
+
+	var result EvaluateResponse
+	c.mustRequest("evaluate", EvaluateArguments{Expression: "x + y", FrameID: 2}, &result)
+	if result.Result != "9" {
+		t.Errorf("wrong result. got=%q", result.Result)
+	}
+	if resp := c.request("evaluate", EvaluateArguments{Expression: "a", FrameID: 1}, nil); resp.Success ||
+		resp.Message != "identifier not found: a" {
+		t.Errorf("evaluating a in <main> should fail. got=%+v", resp)
+	}
+	if resp := c.request("evaluate", EvaluateArguments{Expression: "let = 1"}, nil); resp.Success {
+		t.Errorf("evaluating a parse error should fail. got=%+v", resp)
+	}
+
+	c.mustRequest("continue", map[string]int{"threadId": threadID}, nil)
+	var out OutputEvent
+	c.event("output", &out)
+	if out.Category != "stdout" || out.Output != "6\n" {
+		t.Errorf("wrong output: %+v", out)
+	}
+	c.stopped("breakpoint")
+	c.expectStack([]string{"double", "<main>"}, []int{3, 7})
+
+	c.mustRequest("setBreakpoints", SetBreakpointsArguments{Breakpoints: []SourceBreakpoint{}}, nil)
+	c.mustRequest("continue", map[string]int{"threadId": threadID}, nil)
+	c.finish(12)
+
+	c.mustRequest("disconnect", nil, nil)
+	if err := <-c.done; err != nil {
+		t.Errorf("Serve failed: %s", err)
+	}
+}
+
+func TestStepping(t *testing.T) {
+	c := startServer(t)
+	c.launch(script, LaunchArguments{StopOnEntry: true})
+
+	c.stopped("entry")
+	c.expectStack([]string{"<main>"}, []int{1})
+
+	steps := []struct {
+		command string
+		names   []string
+		lines   []int
+	}{
+		{"next", []string{"<main>"}, []int{5}},
+		{"stepIn", []string{"double", "<main>"}, []int{2, 5}},
+		{"next", []string{"double", "<main>"}, []int{3, 5}},
+		{"stepOut", []string{"<main>"}, []int{6}},
+		{"next", []string{"<main>"}, []int{7}},
+		{"next", []string{"<main>"}, []int{8}},
+	}
+	for _, step := range steps {
+		c.mustRequest(step.command, map[string]int{"threadId": threadID}, nil)
+		c.stopped("step")
+		c.expectStack(step.names, step.lines)
+	}
+
+	c.mustRequest("next", map[string]int{"threadId": threadID}, nil)
+	c.finish(12)
+}
+
+func TestExpandingValues(t *testing.T) {
+	src := "let list = [1, [2, 3]];\nlet h = {\"k\": list, 2: []};\nputs(h)\n"
+	c := startServer(t)
+	c.launch(src, LaunchArguments{}, 3)
+	c.stopped("breakpoint")
+
+	var scopes struct{ Scopes []Scope }
+	c.mustRequest("scopes", ScopesArguments{}, &scopes)
+	if len(scopes.Scopes) != 1 {
+		t.Fatalf("wrong scopes in <main>: %+v", scopes.Scopes)
+	}
+	vars := c.variables(scopes.Scopes[0].VariablesReference)
+
+	h := vars["h"]
+	if h.Value != `{"k": [1, [2, 3]], 2: []}` || h.Type != "HASH" || h.VariablesReference == 0 {
+		t.Fatalf("wrong h: %+v", h)
+	}
+	pairs := c.variables(h.VariablesReference)
+	if pairs[`"k"`].VariablesReference == 0 || pairs["2"].Value != "[]" || pairs["2"].VariablesReference != 0 {
+		t.Fatalf("wrong pairs: %+v", pairs)
+	}
+	elements := c.variables(pairs[`"k"`].VariablesReference)
+	if elements["[0]"].Value != "1" || elements["[1]"].Value != "[2, 3]" {
+		t.Errorf("wrong elements: %+v", elements)
+	}
+
+	var result EvaluateResponse
+	c.mustRequest("evaluate", EvaluateArguments{Expression: "list[1]"}, &result)
+	if result.Result != "[2, 3]" || result.VariablesReference == 0 {
+		t.Errorf("wrong result: %+v", result)
+	}
+	if inner := c.variables(result.VariablesReference); inner["[1]"].Value != "3" {
+		t.Errorf("wrong elements: %+v", inner)
+	}
+
+	// References are dropped when the script resumes.
+	c.mustRequest("continue", nil, nil)
+	c.finish(0)
+	if resp := c.request("variables", VariablesArguments{VariablesReference: 1}, nil); resp.Success {
+		t.Errorf("variables should fail once the script ended: %+v", resp)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	c := startServer(t)
+
+	if resp := c.request("frobnicate", nil, nil); resp.Success || resp.Message != "unsupported command frobnicate" {
+		t.Errorf("wrong response: %+v", resp)
+	}
+	if resp := c.request("launch", LaunchArguments{Program: filepath.Join(t.TempDir(), "missing.cth")}, nil); resp.Success {
+		t.Errorf("launching a missing file should fail: %+v", resp)
+	}
+	if resp := c.request("stackTrace", StackTraceArguments{ThreadID: threadID}, nil); resp.Success ||
+		resp.Message != "the script is not stopped" {
+		t.Errorf("wrong response: %+v", resp)
+	}
+
+	c.launch("puts(1);\n1 + true\n", LaunchArguments{NoDebug: true}, 1)
+	var out OutputEvent
+	c.event("output", &out)
+	c.event("output", &out)
+	if out.Category != "stderr" || out.Output != "ERROR: type mismatch: INTEGER + BOOLEAN\n" {
+		t.Errorf("wrong output: %+v", out)
+	}
+	c.finish(1)
+}
+
+func TestParseErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.cth")
+	if err := os.WriteFile(path, []byte("let = 1;"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := startServer(t)
+	c.mustRequest("initialize", nil, nil)
+	resp := c.request("launch", LaunchArguments{Program: path}, nil)
+	if resp.Success || resp.Message != "parser errors:\n\texpected next token to be IDENT, got = instead\n\tno parse prefix function for =" {
+		t.Errorf("wrong response: %+v", resp)
+	}
+}
+
+func TestDisconnectWhileStopped(t *testing.T) {
+	c := startServer(t)
+	c.launch(script, LaunchArguments{}, 2)
+	c.stopped("breakpoint")
+
+	c.mustRequest("disconnect", nil, nil)
+	c.event("terminated", nil)
+	if err := <-c.done; err != nil {
+		t.Errorf("Serve failed: %s", err)
+	}
+}
+
+func TestMessageTooLarge(t *testing.T) {
+	in := strings.NewReader("Content-Length: 9999999999999999\r\n\r\n{}")
+	err := (&Server{}).Serve(in, io.Discard)
+	if !errors.Is(err, wire.ErrTooLarge) {
+		t.Errorf("Serve returned %v, want wire.ErrTooLarge", err)
+	}
+}
+
+func TestServeConns(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- ServeConns(l) }()
+
+	// A client sending garbage ends only its own session.
+	bad, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(bad, "Content-Length: 9999999999999999\r\n\r\n{}")
+	bad.(*net.TCPConn).CloseWrite()
+	io.Copy(io.Discard, bad)
+	bad.Close()
+
+	netConn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newTestClient(t, newConn(netConn, netConn), nil, func() { netConn.Close() })
+	c.launch(script, LaunchArguments{}, 8)
+	c.stopped("breakpoint")
+	c.expectStack([]string{"<main>"}, []int{8})
+	c.mustRequest("disconnect", nil, nil)
+
+	l.Close()
+	if err := <-served; err != nil {
+		t.Errorf("ServeConns failed: %s", err)
+	}
+}
@@ -0,0 +1,392 @@
+// Package debugger steps through scripts from a terminal. It sits on the
+// evaluator's Hook, pausing the script before a statement and reading
+// commands until one of them resumes it.
+package debugger
+
+import (
+	"bufio"
+	"cathon/ast"
+	"cathon/evaluator"
+	"cathon/lexer"
+	"cathon/object"
+	"cathon/parser"
+	"cathon/pretty"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const PROMPT = "(debug) "
+
+const HELP = `Commands:
+  break LINE     stop before line LINE (b)
+  clear LINE     remove the breakpoint at LINE
+  breakpoints    list the breakpoints
+  continue       run to the next breakpoint (c)
+  step           run to the next line, going into calls (s)
+  next           run to the next line in this function or a caller (n)
+  out            run until this function returns (o)
+  backtrace      show the calls that led here (bt)
+  frame N        print and list in frame N of the backtrace (f)
+  locals         show the variables of the frame
+  print EXPR     evaluate EXPR in the frame and show the result (p)
+  list           show the source around the line (l)
+  quit           stop the script (q)
+`
+
+// errQuit unwinds the script when the user quits.
+var errQuit = errors.New("debugger: quit")
+
+// mode is what the script runs until.
+type mode int
+
+const (
+	modeContinue mode = iota // a breakpoint
+	modeStep                 // any new line
+	modeNext                 // a new line at the same depth or above
+	modeOut                  // a new line above the current depth
+)
+
+// frame is a function call in progress, or the program itself.
+type frame struct {
+	name string
+	env  *object.Environment
+	line int // of the statement running in it
+}
+
+// Debugger runs a script under the user's control.
+type Debugger struct {
+	in     *bufio.Scanner
+	out    io.Writer
+	lines  []string
+	interp *evaluator.Interpreter
+
+	breakpoints map[int]bool
+	frames      []*frame
+
+	mode  mode
+	depth int // of the frame the step started in
+
+	// lastLine and lastDepth are where the previous statement was, so a
+	// line with several statements stops only once.
+	lastLine, lastDepth int
+
+	selected   int  // frame shown by print and locals, 0 being the top
+	evaluating bool // while print runs, events are ignored
+}
+
+// New returns a debugger for source that reads commands from in and writes
+// to out. The script starts paused at its first line.
+func New(source string, in io.Reader, out io.Writer) *Debugger {
+	return &Debugger{
+		in:          bufio.NewScanner(in),
+		out:         out,
+		lines:       strings.Split(strings.TrimSuffix(source, "\n"), "\n"),
+		breakpoints: make(map[int]bool),
+		mode:        modeStep,
+	}
+}
+
+// Break sets a breakpoint before line.
+func (d *Debugger) Break(line int) {
+	d.breakpoints[line] = true
+}
+
+// Run evaluates program in env with interp under the debugger. It returns
+// the result of the program, or nil if the user quit.
+func (d *Debugger) Run(interp *evaluator.Interpreter, program *ast.Program, env *object.Environment) (result object.Object) {
+	d.interp = interp
+	d.frames = []*frame{{name: "<main>", env: env}}
+
+	interp.Hook = d
+	defer func() {
+		interp.Hook = nil
+		if r := recover(); r != nil {
+			if r != errQuit {
+				panic(r)
+			}
+			result = nil
+		}
+	}()
+
+	return interp.Eval(program, env)
+}
+
+// Statement stops before stmt when a breakpoint or a step says so.
+func (d *Debugger) Statement(stmt ast.Statement, pos evaluator.Position, env *object.Environment) {
+	if d.evaluating {
+		return
+	}
+
+	depth := len(d.frames) - 1
+	d.frames[depth].line = pos.Line
+	newLine := pos.Line != d.lastLine || depth != d.lastDepth
+	d.lastLine, d.lastDepth = pos.Line, depth
+	if !newLine {
+		return
+	}
+
+	switch {
+	case d.breakpoints[pos.Line]:
+		fmt.Fprintf(d.out, "breakpoint at line %d\n", pos.Line)
+	case d.mode == modeStep,
+		d.mode == modeNext && depth <= d.depth,
+		d.mode == modeOut && depth < d.depth:
+	default:
+		return
+	}
+
+	d.stop()
+}
+
+// Call pushes a frame for the function.
+func (d *Debugger) Call(call *ast.CallExpression, fn object.Object, pos evaluator.Position, env *object.Environment) {
+	if d.evaluating {
+		return
+	}
+
+	name := evaluator.CallName(call)
+	if _, ok := fn.(*object.Builtin); ok {
+		name += " (builtin)"
+	}
+	d.frames = append(d.frames, &frame{name: name, env: env, line: pos.Line})
+}
+
+// Return pops the frame of the function.
+func (d *Debugger) Return(fn object.Object, result object.Object) {
+	if d.evaluating {
+		return
+	}
+	d.frames = d.frames[:len(d.frames)-1]
+}
+
+// stop shows where the script is and reads commands until one resumes it.
+func (d *Debugger) stop() {
+	d.selected = 0
+	d.showLine(d.frames[len(d.frames)-1].line)
+
+	for {
+		fmt.Fprint(d.out, PROMPT)
+		if !d.in.Scan() {
+			fmt.Fprintln(d.out)
+			panic(errQuit)
+		}
+
+		fields := strings.Fields(d.in.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		name, arg := fields[0], strings.TrimSpace(strings.TrimPrefix(d.in.Text(), fields[0]))
+		if alias, ok := aliases[name]; ok {
+			name = alias
+		}
+
+		cmd, ok := commands[name]
+		if !ok {
+			fmt.Fprintf(d.out, "unknown command %s, type help for a list\n", name)
+			continue
+		}
+		if cmd.needsArg && arg == "" {
+			fmt.Fprintf(d.out, "%s needs an argument, see help\n", name)
+			continue
+		}
+		if cmd.run(d, arg) {
+			return
+		}
+	}
+}
+
+// command is a debugger command. run reports whether the script resumes.
+type command struct {
+	needsArg bool
+	run      func(d *Debugger, arg string) bool
+}
+
+var aliases = map[string]string{
+	"b": "break", "c": "continue", "s": "step", "n": "next", "o": "out",
+	"bt": "backtrace", "f": "frame", "p": "print", "l": "list", "q": "quit",
+	"h": "help", "finish": "out", "where": "backtrace",
+}
+
+var commands = map[string]command{
+	"help": {run: func(d *Debugger, arg string) bool {
+		fmt.Fprint(d.out, HELP)
+		return false
+	}},
+	"break": {needsArg: true, run: func(d *Debugger, arg string) bool {
+		line, ok := d.lineArg(arg)
+		if ok {
+			d.breakpoints[line] = true
+			fmt.Fprintf(d.out, "breakpoint at line %d\n", line)
+		}
+		return false
+	}},
+	"clear": {needsArg: true, run: func(d *Debugger, arg string) bool {
+		line, ok := d.lineArg(arg)
+		if !ok {
+			return false
+		}
+		if !d.breakpoints[line] {
+			fmt.Fprintf(d.out, "no breakpoint at line %d\n", line)
+			return false
+		}
+		delete(d.breakpoints, line)
+		fmt.Fprintf(d.out, "cleared breakpoint at line %d\n", line)
+		return false
+	}},
+	"breakpoints": {run: func(d *Debugger, arg string) bool {
+		if len(d.breakpoints) == 0 {
+			fmt.Fprintln(d.out, "no breakpoints")
+			return false
+		}
+		lines := make([]int, 0, len(d.breakpoints))
+		for line := range d.breakpoints {
+			lines = append(lines, line)
+		}
+		sort.Ints(lines)
+		for _, line := range lines {
+			d.showLine(line)
+		}
+		return false
+	}},
+	"continue": {run: func(d *Debugger, arg string) bool {
+		return d.resume(modeContinue)
+	}},
+	"step": {run: func(d *Debugger, arg string) bool {
+		return d.resume(modeStep)
+	}},
+	"next": {run: func(d *Debugger, arg string) bool {
+		return d.resume(modeNext)
+	}},
+	"out": {run: func(d *Debugger, arg string) bool {
+		if len(d.frames) == 1 {
+			fmt.Fprintln(d.out, "not in a function")
+			return false
+		}
+		return d.resume(modeOut)
+	}},
+	"backtrace": {run: func(d *Debugger, arg string) bool {
+		for i := range d.frames {
+			f := d.frames[len(d.frames)-1-i]
+			marker := " "
+			if i == d.selected {
+				marker = "*"
+			}
+			fmt.Fprintf(d.out, "%s#%d %s at line %d\n", marker, i, f.name, f.line)
+		}
+		return false
+	}},
+	"frame": {needsArg: true, run: func(d *Debugger, arg string) bool {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 || n >= len(d.frames) {
+			fmt.Fprintf(d.out, "no frame %s, see backtrace\n", arg)
+			return false
+		}
+		d.selected = n
+		f := d.frame()
+		fmt.Fprintf(d.out, "#%d %s\n", n, f.name)
+		d.showLine(f.line)
+		return false
+	}},
+	"locals": {run: func(d *Debugger, arg string) bool {
+		env := d.frame().env
+		if env == nil {
+			fmt.Fprintln(d.out, "no variables in a builtin")
+			return false
+		}
+		names := env.LocalNames()
+		if len(names) == 0 {
+			fmt.Fprintln(d.out, "no variables")
+		}
+		for _, name := range names {
+			value, _ := env.Get(name)
+			fmt.Fprintf(d.out, "%s = %s\n", name, pretty.Sprint(value, pretty.Options{}))
+		}
+		return false
+	}},
+	"print": {needsArg: true, run: func(d *Debugger, arg string) bool {
+		d.print(arg)
+		return false
+	}},
+	"list": {run: func(d *Debugger, arg string) bool {
+		d.list(d.frame().line)
+		return false
+	}},
+	"quit": {run: func(d *Debugger, arg string) bool {
+		panic(errQuit)
+	}},
+}
+
+func (d *Debugger) resume(m mode) bool {
+	d.mode = m
+	d.depth = len(d.frames) - 1
+	return true
+}
+
+// frame returns the selected frame.
+func (d *Debugger) frame() *frame {
+	return d.frames[len(d.frames)-1-d.selected]
+}
+
+func (d *Debugger) lineArg(arg string) (int, bool) {
+	line, err := strconv.Atoi(arg)
+	if err != nil || line < 1 || line > len(d.lines) {
+		fmt.Fprintf(d.out, "no line %s in the script\n", arg)
+		return 0, false
+	}
+	return line, true
+}
+
+// print evaluates the expression src in the selected frame. Events from
+// functions it calls are ignored, so it never stops.
+func (d *Debugger) print(src string) {
+	env := d.frame().env
+	if env == nil {
+		env = d.frames[0].env
+	}
+
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		for _, msg := range p.Errors() {
+			fmt.Fprintf(d.out, "\t%s\n", msg)
+		}
+		return
+	}
+
+	d.evaluating = true
+	result := d.interp.Eval(program, env)
+	d.evaluating = false
+
+	if result == nil {
+		result = object.NULL
+	}
+	fmt.Fprintln(d.out, pretty.Sprint(result, pretty.Options{}))
+}
+
+// showLine writes line of the source with its number.
+func (d *Debugger) showLine(line int) {
+	if line < 1 || line > len(d.lines) {
+		return
+	}
+	fmt.Fprintf(d.out, "%4d  %s\n", line, d.lines[line-1])
+}
+
+// list writes the lines around line, marking it with => and breakpoints
+// with *.
+func (d *Debugger) list(line int) {
+	start, end := max(line-5, 1), min(line+5, len(d.lines))
+	for i := start; i <= end; i++ {
+		marker := "  "
+		switch {
+		case i == line:
+			marker = "=>"
+		case d.breakpoints[i]:
+			marker = "* "
+		}
+		fmt.Fprintf(d.out, "%s%4d  %s\n", marker, i, d.lines[i-1])
+	}
+}
@@ -0,0 +1,186 @@
+package debugger
+
+import (
+	"bytes"
+	"cathon/evaluator"
+	"cathon/lexer"
+	"cathon/object"
+	"cathon/parser"
+	"strings"
+	"testing"
+)
+
+const script = `let double = fn(x) {
+  let y = x * 2;
+  y
+};
+let a = double(3);
+puts(a);
+let b = double(a);
+b
+`
+
+// session runs script with commands as the input and returns the result
+// and everything the debugger and the script wrote.
+func session(t *testing.T, commands string, breakpoints ...int) (object.Object, string) {
+	t.Helper()
+
+	p := parser.New(lexer.New(script))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	var out bytes.Buffer
+	interp := evaluator.New()
+	interp.Out = &out
+	d := New(script, strings.NewReader(commands), &out)
+	for _, line := range breakpoints {
+		d.Break(line)
+	}
+	return d.Run(interp, program, object.NewEnvironment()), out.String()
+}
+
+func TestStepping(t *testing.T) {
+	tests := []struct {
+		commands string
+		expected string
+	}{
+		{"step\nstep\nstep\nout\ncontinue\n",
+			"   1  let double = fn(x) {\n(debug) " +
+				"   5  let a = double(3);\n(debug) " +
+				"   2    let y = x * 2;\n(debug) " +
+				"   3    y\n(debug) " +
+				"   6  puts(a);\n(debug) 6\n"},
+		{"next\nnext\nnext\nnext\nnext\n",
+			"   1  let double = fn(x) {\n(debug) " +
+				"   5  let a = double(3);\n(debug) " +
+				"   6  puts(a);\n(debug) 6\n" +
+				"   7  let b = double(a);\n(debug) " +
+				"   8  b\n(debug) "},
+		{"out\ns\ns\ns\nfinish\nc\n",
+			"   1  let double = fn(x) {\n(debug) not in a function\n(debug) " +
+				"   5  let a = double(3);\n(debug) " +
+				"   2    let y = x * 2;\n(debug) " +
+				"   3    y\n(debug) " +
+				"   6  puts(a);\n(debug) 6\n"},
+	}
+
+	for _, tt := range tests {
+		result, out := session(t, tt.commands)
+		if out != tt.expected {
+			t.Errorf("wrong output for %q.\nexpected=%q\ngot=%q", tt.commands, tt.expected, out)
+		}
+		integer, ok := result.(*object.Integer)
+		if !ok || integer.Value != 12 {
+			t.Errorf("wrong result for %q. got=%v", tt.commands, result)
+		}
+	}
+}
+
+func TestBreakpoints(t *testing.T) {
+	result, out := session(t, "break 3\nb 99\nc\nc\nclear 3\nclear 3\nc\n")
+	expected := "   1  let double = fn(x) {\n" +
+		"(debug) breakpoint at line 3\n" +
+		"(debug) no line 99 in the script\n" +
+		"(debug) breakpoint at line 3\n   3    y\n" +
+		"(debug) 6\nbreakpoint at line 3\n   3    y\n" +
+		"(debug) cleared breakpoint at line 3\n" +
+		"(debug) no breakpoint at line 3\n" +
+		"(debug) "
+	if out != expected {
+		t.Errorf("wrong output.\nexpected=%q\ngot=%q", expected, out)
+	}
+	if integer, ok := result.(*object.Integer); !ok || integer.Value != 12 {
+		t.Errorf("wrong result. got=%v", result)
+	}
+}
+
+func TestInspecting(t *testing.T) {
+	_, out := session(t, "c\nbt\nprint x\nprint y + 1\nlocals\nframe 1\np a\nlocals\nframe 2\nlist\nbreakpoints\nq\n", 3)
+	expected := "   1  let double = fn(x) {\n" +
+		"(debug) breakpoint at line 3\n   3    y\n" +
+		"(debug) *#0 double at line 3\n #1 <main> at line 5\n" +
+		"(debug) 3\n" +
+		"(debug) 7\n" +
+		"(debug) x = 3\ny = 6\n" +
+		"(debug) #1 <main>\n   5  let a = double(3);\n" +
+		"(debug) ERROR: identifier not found: a\n" +
+		"(debug) double = fn(x) {...}\n" +
+		"(debug) no frame 2, see backtrace\n" +
+		"(debug)      1  let double = fn(x) {\n" +
+		"     2    let y = x * 2;\n" +
+		"*    3    y\n" +
+		"     4  };\n" +
+		"=>   5  let a = double(3);\n" +
+		"     6  puts(a);\n" +
+		"     7  let b = double(a);\n" +
+		"     8  b\n" +
+		"(debug)    3    y\n" +
+		"(debug) "
+	if out != expected {
+		t.Errorf("wrong output.\nexpected=%q\ngot=%q", expected, out)
+	}
+}
+
+func TestModuleCalls(t *testing.T) {
+	source := `let shout = fn(s) {
+  string.upper(s)
+};
+shout("a")
+`
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	var out bytes.Buffer
+	interp := evaluator.New()
+	d := New(source, strings.NewReader("step\nstep\nstep\n"), &out)
+
+	// upper shows the backtrace from inside the call.
+	module := interp.Builtins()["string"].(*object.Module)
+	upper := module.Members["upper"].(*object.Builtin)
+	module.Members["upper"] = &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		commands["backtrace"].run(d, "")
+		return upper.Fn(args...)
+	}}
+
+	result := d.Run(interp, program, object.NewEnvironment())
+	if result == nil || result.Inspect() != "A" {
+		t.Errorf("wrong result. got=%v", result)
+	}
+
+	expected := "   1  let shout = fn(s) {\n" +
+		"(debug)    4  shout(\"a\")\n" +
+		"(debug)    2    string.upper(s)\n" +
+		"(debug) *#0 string.upper (builtin) at line 2\n" +
+		" #1 shout at line 2\n" +
+		" #2 <main> at line 4\n"
+	if out.String() != expected {
+		t.Errorf("wrong output.\nexpected=%q\ngot=%q", expected, out.String())
+	}
+}
+
+func TestQuit(t *testing.T) {
+	for _, commands := range []string{"quit\n", ""} {
+		result, out := session(t, commands)
+		if result != nil {
+			t.Errorf("expected no result for %q. got=%v", commands, result)
+		}
+		if strings.Contains(out, "6\n") {
+			t.Errorf("script kept running after %q: %q", commands, out)
+		}
+	}
+}
+
+func TestPrintDoesNotStop(t *testing.T) {
+	_, out := session(t, "s\np double(2)\nq\n", 2)
+	expected := "   1  let double = fn(x) {\n" +
+		"(debug)    5  let a = double(3);\n" +
+		"(debug) 4\n(debug) "
+	if out != expected {
+		t.Errorf("wrong output.\nexpected=%q\ngot=%q", expected, out)
+	}
+}
@@ -3,6 +3,9 @@ package evaluator
 import (
 	"cathon/object"
 	"fmt"
+	"io"
+	"sort"
+	"unicode/utf8"
 )
 
 var builtins = map[string]*object.Builtin{
@@ -16,22 +19,13 @@ var builtins = map[string]*object.Builtin{
 		case *object.Array:
 			return &object.Integer{Value: int64(len(arg.Elements))}
 		case *object.String:
-			return &object.Integer{Value: int64(len(arg.Value))}
+			return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
 		default:
 			return newError("argument to `len` not supported, got %s",
 				args[0].Type())
 		}
 	},
 	},
-	"puts": {
-		Fn: func(args ...object.Object) object.Object {
-			for _, arg := range args {
-				fmt.Println(arg.Inspect())
-			}
-
-			return NULL
-		},
-	},
 	"first": {
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
@@ -114,4 +108,491 @@ var builtins = map[string]*object.Builtin{
 			return &object.Array{Elements: newElements}
 		},
 	},
+	"zip": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) == 0 {
+				return newError("wrong number of arguments. got=0, want at least 1")
+			}
+
+			length := -1
+			for _, arg := range args {
+				arr, ok := arg.(*object.Array)
+				if !ok {
+					return newError("argument to `zip` must be ARRAY, got %s",
+						arg.Type())
+				}
+				if length < 0 || len(arr.Elements) < length {
+					length = len(arr.Elements)
+				}
+			}
+
+			elements := make([]object.Object, length)
+			for i := range elements {
+				tuple := make([]object.Object, len(args))
+				for j, arg := range args {
+					tuple[j] = arg.(*object.Array).Elements[i]
+				}
+				elements[i] = &object.Array{Elements: tuple}
+			}
+
+			return &object.Array{Elements: elements}
+		},
+	},
+	"keys": {
+		Fn: func(args ...object.Object) object.Object {
+			hash, err := hashArg("keys", args)
+			if err != nil {
+				return err
+			}
+
+			pairs := hash.Pairs()
+			elements := make([]object.Object, len(pairs))
+			for i, pair := range pairs {
+				elements[i] = pair.Key
+			}
+
+			return &object.Array{Elements: elements}
+		},
+	},
+	"values": {
+		Fn: func(args ...object.Object) object.Object {
+			hash, err := hashArg("values", args)
+			if err != nil {
+				return err
+			}
+
+			pairs := hash.Pairs()
+			elements := make([]object.Object, len(pairs))
+			for i, pair := range pairs {
+				elements[i] = pair.Value
+			}
+
+			return &object.Array{Elements: elements}
+		},
+	},
+	"entries": {
+		Fn: func(args ...object.Object) object.Object {
+			hash, err := hashArg("entries", args)
+			if err != nil {
+				return err
+			}
+
+			pairs := hash.Pairs()
+			elements := make([]object.Object, len(pairs))
+			for i, pair := range pairs {
+				elements[i] = &object.Array{Elements: []object.Object{pair.Key, pair.Value}}
+			}
+
+			return &object.Array{Elements: elements}
+		},
+	},
+	"has": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2",
+					len(args))
+			}
+			hash, err := hashArg("has", args[:1])
+			if err != nil {
+				return err
+			}
+			if !object.IsHashable(args[1]) {
+				return newError("unusable as hash key: %s", args[1].Type())
+			}
+
+			_, ok := hash.Get(args[1])
+			return nativeBoolToBooleanObject(ok)
+		},
+	},
+	"delete": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2",
+					len(args))
+			}
+			hash, err := hashArg("delete", args[:1])
+			if err != nil {
+				return err
+			}
+			if !object.IsHashable(args[1]) {
+				return newError("unusable as hash key: %s", args[1].Type())
+			}
+
+			result := object.NewHash()
+			for _, pair := range hash.Pairs() {
+				if !pair.Key.Equals(args[1]) {
+					result.Set(pair.Key, pair.Value)
+				}
+			}
+
+			return result
+		},
+	},
+	"merge": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) == 0 {
+				return newError("wrong number of arguments. got=0, want at least 1")
+			}
+
+			result := object.NewHash()
+			for _, arg := range args {
+				hash, ok := arg.(*object.Hash)
+				if !ok {
+					return newError("argument to `merge` must be HASH, got %s",
+						arg.Type())
+				}
+				for _, pair := range hash.Pairs() {
+					result.Set(pair.Key, pair.Value)
+				}
+			}
+
+			return result
+		},
+	},
+	"bytes": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+			if args[0].Type() != object.STRING_OBJ {
+				return newError("argument to `bytes` must be STRING, got %s",
+					args[0].Type())
+			}
+
+			str := args[0].(*object.String).Value
+			elements := make([]object.Object, len(str))
+			for i := 0; i < len(str); i++ {
+				elements[i] = &object.Integer{Value: int64(str[i])}
+			}
+
+			return &object.Array{Elements: elements}
+		},
+	},
+	"runes": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+			if args[0].Type() != object.STRING_OBJ {
+				return newError("argument to `runes` must be STRING, got %s",
+					args[0].Type())
+			}
+
+			runes := []rune(args[0].(*object.String).Value)
+			elements := make([]object.Object, len(runes))
+			for i, r := range runes {
+				elements[i] = &object.Integer{Value: int64(r)}
+			}
+
+			return &object.Array{Elements: elements}
+		},
+	},
+	"sprintf": {
+		Fn: func(args ...object.Object) object.Object {
+			return format("sprintf", args)
+		},
+	},
+	"type": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			return &object.String{Value: string(args[0].Type())}
+		},
+	},
+	"str":   conversionBuiltin(object.STRING_OBJ),
+	"int":   conversionBuiltin(object.INTEGER_OBJ),
+	"float": conversionBuiltin(object.FLOAT_OBJ),
+	"bool":  conversionBuiltin(object.BOOLEAN_OBJ),
+
+	"isInt":      typePredicate(object.INTEGER_OBJ),
+	"isFloat":    typePredicate(object.FLOAT_OBJ),
+	"isNumber":   typePredicate(object.INTEGER_OBJ, object.FLOAT_OBJ),
+	"isString":   typePredicate(object.STRING_OBJ),
+	"isBool":     typePredicate(object.BOOLEAN_OBJ),
+	"isArray":    typePredicate(object.ARRAY_OBJ),
+	"isHash":     typePredicate(object.HASH_OBJ),
+	"isNull":     typePredicate(object.NULL_OBJ),
+	"isFunction": typePredicate(object.FUNCTION_OBJ, object.BUILTIN_OBJ),
+}
+
+// modules are namespaces of builtins, reached as string.split.
+var modules = map[string]map[string]object.Object{
+	"string": stringBuiltins,
+	"math":   mathBuiltins,
+	"json":   jsonBuiltins,
+}
+
+// interpreterModules are the module members that, like interpreterBuiltins,
+// are bound to each Interpreter.
+var interpreterModules = map[string]map[string]func(in *Interpreter, args ...object.Object) object.Object{
+	"math": mathInterpreterBuiltins,
+}
+
+// interpreterBuiltins need the Interpreter that calls them, for example to
+// call back into script functions. New binds them to each Interpreter.
+var interpreterBuiltins = map[string]func(in *Interpreter, args ...object.Object) object.Object{
+	"puts": func(in *Interpreter, args ...object.Object) object.Object {
+		for _, arg := range args {
+			fmt.Fprintln(in.Out, arg.Inspect())
+		}
+
+		return NULL
+	},
+	"printf": func(in *Interpreter, args ...object.Object) object.Object {
+		str := format("printf", args)
+		if isError(str) {
+			return str
+		}
+
+		io.WriteString(in.Out, str.(*object.String).Value)
+		return NULL
+	},
+	"map": func(in *Interpreter, args ...object.Object) object.Object {
+		arr, fn, err := arrayAndFunctionArgs("map", args)
+		if err != nil {
+			return err
+		}
+
+		elements := make([]object.Object, len(arr.Elements))
+		for i, el := range arr.Elements {
+			result := in.applyFunction(fn, []object.Object{el})
+			if isError(result) {
+				return result
+			}
+			elements[i] = result
+		}
+
+		return &object.Array{Elements: elements}
+	},
+	"filter": func(in *Interpreter, args ...object.Object) object.Object {
+		arr, fn, err := arrayAndFunctionArgs("filter", args)
+		if err != nil {
+			return err
+		}
+
+		elements := []object.Object{}
+		for _, el := range arr.Elements {
+			result := in.applyFunction(fn, []object.Object{el})
+			if isError(result) {
+				return result
+			}
+			if isTruthy(result) {
+				elements = append(elements, el)
+			}
+		}
+
+		return &object.Array{Elements: elements}
+	},
+	"reduce": func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 2 && len(args) != 3 {
+			return newError("wrong number of arguments. got=%d, want=2 or 3",
+				len(args))
+		}
+		arr, fn, err := arrayAndFunctionArgs("reduce", args[:2])
+		if err != nil {
+			return err
+		}
+
+		elements := arr.Elements
+		var acc object.Object
+		if len(args) == 3 {
+			acc = args[2]
+		} else {
+			if len(elements) == 0 {
+				return newError("`reduce` of empty ARRAY with no initial value")
+			}
+			acc, elements = elements[0], elements[1:]
+		}
+
+		for _, el := range elements {
+			acc = in.applyFunction(fn, []object.Object{acc, el})
+			if isError(acc) {
+				return acc
+			}
+		}
+
+		return acc
+	},
+	"sort": func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 1 && len(args) != 2 {
+			return newError("wrong number of arguments. got=%d, want=1 or 2",
+				len(args))
+		}
+		if args[0].Type() != object.ARRAY_OBJ {
+			return newError("argument to `sort` must be ARRAY, got %s",
+				args[0].Type())
+		}
+
+		arr := args[0].(*object.Array)
+		elements := make([]object.Object, len(arr.Elements))
+		copy(elements, arr.Elements)
+
+		less := naturalLess
+		if len(args) == 2 {
+			if !isCallable(args[1]) {
+				return newError("argument to `sort` must be FUNCTION, got %s",
+					args[1].Type())
+			}
+			less = func(a, b object.Object) (bool, *object.Error) {
+				result := in.applyFunction(args[1], []object.Object{a, b})
+				if err, ok := result.(*object.Error); ok {
+					return false, err
+				}
+				if result.Type() != object.BOOLEAN_OBJ {
+					return false, newError("comparator to `sort` must return BOOLEAN, got %s",
+						result.Type())
+				}
+				return result == TRUE, nil
+			}
+		}
+
+		// sort.SliceStable can't be stopped, so after the first error every
+		// comparison reports false and the error is returned afterwards.
+		var sortErr *object.Error
+		sort.SliceStable(elements, func(i, j int) bool {
+			if sortErr != nil {
+				return false
+			}
+			result, err := less(elements[i], elements[j])
+			if err != nil {
+				sortErr = err
+			}
+			return result
+		})
+		if sortErr != nil {
+			return sortErr
+		}
+
+		return &object.Array{Elements: elements}
+	},
+	"find": func(in *Interpreter, args ...object.Object) object.Object {
+		arr, fn, err := arrayAndFunctionArgs("find", args)
+		if err != nil {
+			return err
+		}
+
+		for _, el := range arr.Elements {
+			result := in.applyFunction(fn, []object.Object{el})
+			if isError(result) {
+				return result
+			}
+			if isTruthy(result) {
+				return el
+			}
+		}
+
+		return NULL
+	},
+	"any": func(in *Interpreter, args ...object.Object) object.Object {
+		arr, fn, err := arrayAndFunctionArgs("any", args)
+		if err != nil {
+			return err
+		}
+
+		for _, el := range arr.Elements {
+			result := in.applyFunction(fn, []object.Object{el})
+			if isError(result) {
+				return result
+			}
+			if isTruthy(result) {
+				return TRUE
+			}
+		}
+
+		return FALSE
+	},
+	"all": func(in *Interpreter, args ...object.Object) object.Object {
+		arr, fn, err := arrayAndFunctionArgs("all", args)
+		if err != nil {
+			return err
+		}
+
+		for _, el := range arr.Elements {
+			result := in.applyFunction(fn, []object.Object{el})
+			if isError(result) {
+				return result
+			}
+			if !isTruthy(result) {
+				return FALSE
+			}
+		}
+
+		return TRUE
+	},
+}
+
+// checkArgs reports a wrong argument count, or the first argument whose
+// type isn't the one wanted, in the words the builtins above use.
+func checkArgs(name string, args []object.Object, want ...object.ObjectType) *object.Error {
+	if len(args) != len(want) {
+		return newError("wrong number of arguments. got=%d, want=%d",
+			len(args), len(want))
+	}
+	for i, t := range want {
+		if args[i].Type() != t {
+			return newError("argument to `%s` must be %s, got %s",
+				name, t, args[i].Type())
+		}
+	}
+
+	return nil
+}
+
+func arrayAndFunctionArgs(name string, args []object.Object) (*object.Array, object.Object, *object.Error) {
+	if len(args) != 2 {
+		return nil, nil, newError("wrong number of arguments. got=%d, want=2",
+			len(args))
+	}
+	if args[0].Type() != object.ARRAY_OBJ {
+		return nil, nil, newError("argument to `%s` must be ARRAY, got %s",
+			name, args[0].Type())
+	}
+	if !isCallable(args[1]) {
+		return nil, nil, newError("argument to `%s` must be FUNCTION, got %s",
+			name, args[1].Type())
+	}
+
+	return args[0].(*object.Array), args[1], nil
+}
+
+func isCallable(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Function, *object.Builtin, *object.Closure:
+		return true
+	default:
+		return false
+	}
+}
+
+// naturalLess orders numbers numerically and strings lexicographically.
+func naturalLess(a, b object.Object) (bool, *object.Error) {
+	switch {
+	case a.Type() == object.INTEGER_OBJ && b.Type() == object.INTEGER_OBJ:
+		return a.(*object.Integer).Value < b.(*object.Integer).Value, nil
+	case isNumber(a) && isNumber(b):
+		return toFloat(a) < toFloat(b), nil
+	case a.Type() == object.STRING_OBJ && b.Type() == object.STRING_OBJ:
+		return a.(*object.String).Value < b.(*object.String).Value, nil
+	default:
+		return false, newError("`sort` cannot compare %s and %s", a.Type(), b.Type())
+	}
+}
+
+func hashArg(name string, args []object.Object) (*object.Hash, *object.Error) {
+	if len(args) != 1 {
+		return nil, newError("wrong number of arguments. got=%d, want=1",
+			len(args))
+	}
+	hash, ok := args[0].(*object.Hash)
+	if !ok {
+		return nil, newError("argument to `%s` must be HASH, got %s",
+			name, args[0].Type())
+	}
+
+	return hash, nil
 }
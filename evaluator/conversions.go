@@ -0,0 +1,124 @@
+package evaluator
+
+import (
+	"cathon/object"
+	"math"
+	"strconv"
+)
+
+type conversion func(obj object.Object) object.Object
+
+// conversions maps a target type to how each source type converts to it.
+// A new type plugs in by adding its rows here. Sources that aren't listed
+// fall back to anyConversions.
+var conversions = map[object.ObjectType]map[object.ObjectType]conversion{
+	object.INTEGER_OBJ: {
+		object.INTEGER_OBJ: identity,
+		object.FLOAT_OBJ: func(obj object.Object) object.Object {
+			f := obj.(*object.Float).Value
+			i, ok := floatToInteger(math.Trunc(f))
+			if !ok {
+				return newError("%s out of INTEGER range", obj.Inspect())
+			}
+			return &object.Integer{Value: i}
+		},
+		object.STRING_OBJ: func(obj object.Object) object.Object {
+			str := obj.(*object.String).Value
+			i, err := strconv.ParseInt(str, 10, 64)
+			if err != nil {
+				return newError("could not parse %q as INTEGER", str)
+			}
+			return &object.Integer{Value: i}
+		},
+		object.BOOLEAN_OBJ: func(obj object.Object) object.Object {
+			if obj == TRUE {
+				return &object.Integer{Value: 1}
+			}
+			return &object.Integer{Value: 0}
+		},
+	},
+	object.FLOAT_OBJ: {
+		object.FLOAT_OBJ: identity,
+		object.INTEGER_OBJ: func(obj object.Object) object.Object {
+			return &object.Float{Value: toFloat(obj)}
+		},
+		object.STRING_OBJ: func(obj object.Object) object.Object {
+			str := obj.(*object.String).Value
+			f, err := strconv.ParseFloat(str, 64)
+			if err != nil {
+				return newError("could not parse %q as FLOAT", str)
+			}
+			return &object.Float{Value: f}
+		},
+	},
+	object.STRING_OBJ: {
+		object.STRING_OBJ: identity,
+	},
+}
+
+// anyConversions convert every source type that conversions doesn't list.
+var anyConversions = map[object.ObjectType]conversion{
+	object.STRING_OBJ: func(obj object.Object) object.Object {
+		return &object.String{Value: obj.Inspect()}
+	},
+	object.BOOLEAN_OBJ: func(obj object.Object) object.Object {
+		return nativeBoolToBooleanObject(isTruthy(obj))
+	},
+}
+
+func identity(obj object.Object) object.Object { return obj }
+
+// convert converts obj to target or returns an error.
+func convert(obj object.Object, target object.ObjectType) object.Object {
+	if convert, ok := conversions[target][obj.Type()]; ok {
+		return convert(obj)
+	}
+	if convert, ok := anyConversions[target]; ok {
+		return convert(obj)
+	}
+	return newError("cannot convert %s to %s", obj.Type(), target)
+}
+
+// conversionBuiltin is a builtin such as int(x) that converts its one
+// argument to target.
+func conversionBuiltin(target object.ObjectType) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			return convert(args[0], target)
+		},
+	}
+}
+
+// typePredicate is a builtin such as isInt(x) that reports whether its one
+// argument has one of types.
+func typePredicate(types ...object.ObjectType) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			for _, t := range types {
+				if args[0].Type() == t {
+					return TRUE
+				}
+			}
+			return FALSE
+		},
+	}
+}
+
+// floatToInteger converts an integral f, reporting false when it is NaN or
+// out of INTEGER range.
+func floatToInteger(f float64) (int64, bool) {
+	if math.IsNaN(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, false
+	}
+	return int64(f), true
+}
@@ -0,0 +1,79 @@
+package evaluator
+
+import (
+	"cathon/ast"
+	"cathon/object"
+	"cathon/token"
+)
+
+// Position is where a node starts in the source, counting from 1.
+type Position struct {
+	Line, Column int
+}
+
+// A Hook follows an Interpreter as it runs, for debuggers and profilers.
+// Its methods run on the evaluating goroutine, so a debugger can pause the
+// script by not returning.
+type Hook interface {
+	// Statement is called before each statement runs, with the environment
+	// it runs in.
+	Statement(stmt ast.Statement, pos Position, env *object.Environment)
+
+	// Call is called before a function runs. call is the expression that
+	// calls it, or nil when a builtin such as map does. env is the new
+	// environment holding the arguments of a script function, or the
+	// caller's environment for a builtin.
+	Call(call *ast.CallExpression, fn object.Object, pos Position, env *object.Environment)
+
+	// Return is called when the function of the matching Call is done.
+	Return(fn object.Object, result object.Object)
+}
+
+func (in *Interpreter) hookStatement(stmt ast.Statement, env *object.Environment) {
+	var tok token.Token
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		tok = stmt.Token
+	case *ast.ReturnStatement:
+		tok = stmt.Token
+	case *ast.ExpressionStatement:
+		tok = stmt.Token
+	case *ast.BlockStatement:
+		tok = stmt.Token
+	}
+	in.Hook.Statement(stmt, Position{Line: tok.Line, Column: tok.Column}, env)
+}
+
+// CallName names the function call calls for a backtrace, as add in
+// add(1, 2) or string.upper in string.upper(s).
+func CallName(call *ast.CallExpression) string {
+	if call == nil {
+		return "<anonymous>"
+	}
+	if dot, ok := call.Function.(*ast.DotExpression); ok {
+		return dot.Left.String() + "." + dot.Property.Value
+	}
+	return call.Function.String()
+}
+
+// callPosition is where the function called by call is named, as add in
+// add(1, 2) or upper in string.upper(s). Without a call it is the body of
+// a script function.
+func callPosition(call *ast.CallExpression, fn object.Object) Position {
+	var tok token.Token
+	switch {
+	case call != nil:
+		tok = call.Token
+		switch function := call.Function.(type) {
+		case *ast.Identifier:
+			tok = function.Token
+		case *ast.DotExpression:
+			tok = function.Property.Token
+		}
+	default:
+		if fn, ok := fn.(*object.Function); ok {
+			tok = fn.Body.Token
+		}
+	}
+	return Position{Line: tok.Line, Column: tok.Column}
+}
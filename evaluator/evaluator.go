@@ -4,36 +4,115 @@ import (
 	"fmt"
 	"cathon/ast"
 	"cathon/object"
+	"io"
+	"math/rand/v2"
+	"os"
+	"strings"
 )
 
+// maxStringLength caps strings built by repetition so a script can't
+// exhaust memory with "a" * 1000000000000.
+const maxStringLength = 1 << 28
+
 var (
-	NULL  = &object.Null{}
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
+	NULL  = object.NULL
+	TRUE  = object.TRUE
+	FALSE = object.FALSE
 )
 
+// Interpreter evaluates programs. It holds what belongs to one running
+// script rather than to a scope, such as the builtins bound to it and the
+// random source behind math.random.
+type Interpreter struct {
+	// Out receives what puts and printf write. New sets it to os.Stdout.
+	Out io.Writer
+
+	// Hook, if set, is told about each statement and function call.
+	Hook Hook
+
+	// RunClosure runs the closures of compiled scripts, which builtins
+	// such as map may be given. Package vm sets it on the Interpreter
+	// whose builtins it uses.
+	RunClosure func(fn *object.Closure, args []object.Object) object.Object
+
+	builtins map[string]object.Object
+
+	source *rand.PCG
+	rand   *rand.Rand
+}
+
+func New() *Interpreter {
+	in := &Interpreter{Out: os.Stdout, builtins: make(map[string]object.Object)}
+	in.source = rand.NewPCG(defaultSeed, 0)
+	in.rand = rand.New(in.source)
+
+	for name, builtin := range builtins {
+		in.builtins[name] = builtin
+	}
+	for name, fn := range interpreterBuiltins {
+		in.builtins[name] = in.bind(fn)
+	}
+
+	// Each Interpreter gets its own members map, since some members are
+	// bound to it.
+	for name, members := range modules {
+		module := &object.Module{Name: name, Members: make(map[string]object.Object)}
+		for member, value := range members {
+			module.Members[member] = value
+		}
+		for member, fn := range interpreterModules[name] {
+			module.Members[member] = in.bind(fn)
+		}
+		in.builtins[name] = module
+	}
+
+	return in
+}
+
+// Builtins returns the builtins and modules visible to scripts by name.
+func (in *Interpreter) Builtins() map[string]object.Object {
+	builtins := make(map[string]object.Object, len(in.builtins))
+	for name, builtin := range in.builtins {
+		builtins[name] = builtin
+	}
+	return builtins
+}
+
+func (in *Interpreter) bind(fn func(in *Interpreter, args ...object.Object) object.Object) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			return fn(in, args...)
+		},
+	}
+}
+
+// Eval evaluates node in env with a new Interpreter.
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	return New().Eval(node, env)
+}
+
+func (in *Interpreter) Eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 
 	// Statements
 	case *ast.Program:
-		return evalProgram(node, env)
+		return in.evalProgram(node, env)
 
 	case *ast.BlockStatement:
-		return evalBlockStatement(node, env)
+		return in.evalBlockStatement(node, env)
 
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression, env)
+		return in.Eval(node.Expression, env)
 
 	case *ast.ReturnStatement:
-		val := Eval(node.ReturnValue, env)
+		val := in.Eval(node.ReturnValue, env)
 		if isError(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
 
 	case *ast.LetStatement:
-		val := Eval(node.Value, env)
+		val := in.Eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
@@ -43,6 +122,9 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
 
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
+
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
 
@@ -50,19 +132,19 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return nativeBoolToBooleanObject(node.Value)
 
 	case *ast.PrefixExpression:
-		right := Eval(node.Right, env)
+		right := in.Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
 		return evalPrefixExpression(node.Operator, right)
 
 	case *ast.InfixExpression:
-		left := Eval(node.Left, env)
+		left := in.Eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
 
-		right := Eval(node.Right, env)
+		right := in.Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
@@ -70,10 +152,10 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return evalInfixExpression(node.Operator, left, right)
 
 	case *ast.IfExpression:
-		return evalIfExpression(node, env)
+		return in.evalIfExpression(node, env)
 
 	case *ast.Identifier:
-		return evalIdentifier(node, env)
+		return in.evalIdentifier(node, env)
 
 	case *ast.FunctionLiteral:
 		params := node.Parameters
@@ -81,49 +163,69 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return &object.Function{Parameters: params, Env: env, Body: body}
 
 	case *ast.CallExpression:
-		function := Eval(node.Function, env)
+		if dot, ok := node.Function.(*ast.DotExpression); ok {
+			return in.evalMethodCall(node, dot, env)
+		}
+
+		function := in.Eval(node.Function, env)
 		if isError(function) {
 			return function
 		}
 
-		args := evalExpressions(node.Arguments, env)
+		args := in.evalExpressions(node.Arguments, env)
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
 
-		return applyFunction(function, args)
+		return in.apply(node, env, function, args)
 
 	case *ast.ArrayLiteral:
-		elements := evalExpressions(node.Elements, env)
+		elements := in.evalExpressions(node.Elements, env)
 		if len(elements) == 1 && isError(elements[0]) {
 			return elements[0]
 		}
 		return &object.Array{Elements: elements}
 
 	case *ast.IndexExpression:
-		left := Eval(node.Left, env)
+		left := in.Eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
-		index := Eval(node.Index, env)
+		index := in.Eval(node.Index, env)
 		if isError(index) {
 			return index
 		}
 		return evalIndexExpression(left, index)
 
+	case *ast.SliceExpression:
+		return in.evalSliceExpression(node, env)
+
 	case *ast.HashLiteral:
-		return evalHashLiteral(node, env)
+		return in.evalHashLiteral(node, env)
+
+	case *ast.DotExpression:
+		left := in.Eval(node.Left, env)
+		if isError(left) {
+			return left
+		}
+		return evalDotExpression(left, node.Property.Value)
+
+	case *ast.AssignExpression:
+		return in.evalAssignExpression(node, env)
 
 	}
 
 	return nil
 }
 
-func evalProgram(program *ast.Program, env *object.Environment) object.Object {
+func (in *Interpreter) evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range program.Statements {
-		result = Eval(statement, env)
+		if in.Hook != nil {
+			in.hookStatement(statement, env)
+		}
+		result = in.Eval(statement, env)
 
 		switch result := result.(type) {
 		case *object.ReturnValue:
@@ -136,14 +238,17 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	return result
 }
 
-func evalBlockStatement(
+func (in *Interpreter) evalBlockStatement(
 	block *ast.BlockStatement,
 	env *object.Environment,
 ) object.Object {
 	var result object.Object
 
 	for _, statement := range block.Statements {
-		result = Eval(statement, env)
+		if in.Hook != nil {
+			in.hookStatement(statement, env)
+		}
+		result = in.Eval(statement, env)
 
 		if result != nil {
 			rt := result.Type()
@@ -181,12 +286,18 @@ func evalInfixExpression(
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
+	case isNumber(left) && isNumber(right):
+		return evalFloatInfixExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringInfixExpression(operator, left, right)
+	case operator == "*" && left.Type() == object.STRING_OBJ && right.Type() == object.INTEGER_OBJ:
+		return evalStringRepetition(left, right)
+	case operator == "*" && left.Type() == object.INTEGER_OBJ && right.Type() == object.STRING_OBJ:
+		return evalStringRepetition(right, left)
 	case operator == "==":
-		return nativeBoolToBooleanObject(left == right)
+		return nativeBoolToBooleanObject(left.Equals(right))
 	case operator == "!=":
-		return nativeBoolToBooleanObject(left != right)
+		return nativeBoolToBooleanObject(!left.Equals(right))
 	case left.Type() != right.Type():
 		return newError("type mismatch: %s %s %s",
 			left.Type(), operator, right.Type())
@@ -210,12 +321,14 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 }
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
+	switch right := right.(type) {
+	case *object.Integer:
+		return &object.Integer{Value: -right.Value}
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
 		return newError("unknown operator: -%s", right.Type())
 	}
-
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
 }
 
 func evalIntegerInfixExpression(
@@ -233,11 +346,18 @@ func evalIntegerInfixExpression(
 	case "*":
 		return &object.Integer{Value: leftVal * rightVal}
 	case "/":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
 		return &object.Integer{Value: leftVal / rightVal}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
 	case "==":
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
@@ -248,39 +368,117 @@ func evalIntegerInfixExpression(
 	}
 }
 
-func evalStringInfixExpression(
+// evalFloatInfixExpression handles two floats, or a float and an integer,
+// which is widened to a float first.
+func evalFloatInfixExpression(
 	operator string,
 	left, right object.Object,
 ) object.Object {
-	if operator != "+" {
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
+
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		return &object.Float{Value: leftVal / rightVal}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
 		return newError("unknown operator: %s %s %s",
 			left.Type(), operator, right.Type())
 	}
+}
 
+func isNumber(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.FLOAT_OBJ
+}
+
+// toFloat widens an Integer or Float; callers check isNumber first.
+func toFloat(obj object.Object) float64 {
+	if i, ok := obj.(*object.Integer); ok {
+		return float64(i.Value)
+	}
+	return obj.(*object.Float).Value
+}
+
+func evalStringInfixExpression(
+	operator string,
+	left, right object.Object,
+) object.Object {
 	leftVal := left.(*object.String).Value
 	rightVal := right.(*object.String).Value
-	return &object.String{Value: leftVal + rightVal}
+
+	switch operator {
+	case "+":
+		return &object.String{Value: leftVal + rightVal}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	case "in":
+		return nativeBoolToBooleanObject(strings.Contains(rightVal, leftVal))
+	default:
+		return newError("unknown operator: %s %s %s",
+			left.Type(), operator, right.Type())
+	}
 }
 
-func evalIfExpression(
+func evalStringRepetition(str, count object.Object) object.Object {
+	value := str.(*object.String).Value
+	n := count.(*object.Integer).Value
+
+	if n <= 0 || len(value) == 0 {
+		return &object.String{Value: ""}
+	}
+	if n > maxStringLength/int64(len(value)) {
+		return newError("string repetition too long: %d * %d", len(value), n)
+	}
+
+	return &object.String{Value: strings.Repeat(value, int(n))}
+}
+
+func (in *Interpreter) evalIfExpression(
 	ie *ast.IfExpression,
 	env *object.Environment,
 ) object.Object {
-	condition := Eval(ie.Condition, env)
+	condition := in.Eval(ie.Condition, env)
 	if isError(condition) {
 		return condition
 	}
 
 	if isTruthy(condition) {
-		return Eval(ie.Consequence, env)
+		return in.Eval(ie.Consequence, env)
 	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
+		return in.Eval(ie.Alternative, env)
 	} else {
 		return NULL
 	}
 }
 
-func evalIdentifier(
+func (in *Interpreter) evalIdentifier(
 	node *ast.Identifier,
 	env *object.Environment,
 ) object.Object {
@@ -288,7 +486,7 @@ func evalIdentifier(
 		return val
 	}
 
-	if builtin, ok := builtins[node.Value]; ok {
+	if builtin, ok := in.builtins[node.Value]; ok {
 		return builtin
 	}
 
@@ -319,14 +517,14 @@ func isError(obj object.Object) bool {
 	return false
 }
 
-func evalExpressions(
+func (in *Interpreter) evalExpressions(
 	exps []ast.Expression,
 	env *object.Environment,
 ) []object.Object {
 	var result []object.Object
 
 	for _, e := range exps {
-		evaluated := Eval(e, env)
+		evaluated := in.Eval(e, env)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
 		}
@@ -336,16 +534,54 @@ func evalExpressions(
 	return result
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+func (in *Interpreter) applyFunction(fn object.Object, args []object.Object) object.Object {
+	return in.apply(nil, nil, fn, args)
+}
+
+// apply calls fn for call, which is nil when a builtin such as map calls
+// fn. env is the caller's environment, if known.
+func (in *Interpreter) apply(
+	call *ast.CallExpression,
+	env *object.Environment,
+	fn object.Object,
+	args []object.Object,
+) object.Object {
 	switch fn := fn.(type) {
 
 	case *object.Function:
+		if len(args) != len(fn.Parameters) {
+			return newError("wrong number of arguments. got=%d, want=%d",
+				len(args), len(fn.Parameters))
+		}
+
 		extendedEnv := extendFunctionEnv(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
-		return unwrapReturnValue(evaluated)
+		if in.Hook != nil {
+			in.Hook.Call(call, fn, callPosition(call, fn), extendedEnv)
+		}
+		evaluated := in.Eval(fn.Body, extendedEnv)
+		if evaluated == nil {
+			evaluated = NULL
+		}
+		result := unwrapReturnValue(evaluated)
+		if in.Hook != nil {
+			in.Hook.Return(fn, result)
+		}
+		return result
 
 	case *object.Builtin:
-		return fn.Fn(args...)
+		if in.Hook == nil {
+			return fn.Fn(args...)
+		}
+		in.Hook.Call(call, fn, callPosition(call, fn), env)
+		result := fn.Fn(args...)
+		in.Hook.Return(fn, result)
+		return result
+
+	case *object.Closure:
+		if in.RunClosure == nil {
+			return newError("not a function: %s", fn.Type())
+		}
+		return in.RunClosure(fn, args)
 
 	default:
 		return newError("not a function: %s", fn.Type())
@@ -377,6 +613,8 @@ func evalIndexExpression(left, index object.Object) object.Object {
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalStringIndexExpression(left, index)
 	case left.Type() == object.HASH_OBJ:
 		return evalHashIndexExpression(left, index)
 	default:
@@ -396,47 +634,252 @@ func evalArrayIndexExpression(array, index object.Object) object.Object {
 	return arrayObject.Elements[idx]
 }
 
-func evalHashLiteral(
+// Strings are indexed by code point, and negative indexes count back
+// from the end: "고양이"[-1] is "이".
+func evalStringIndexExpression(str, index object.Object) object.Object {
+	runes := []rune(str.(*object.String).Value)
+	idx := index.(*object.Integer).Value
+	length := int64(len(runes))
+
+	if idx < 0 {
+		idx += length
+	}
+	if idx < 0 || idx >= length {
+		return NULL
+	}
+
+	return &object.String{Value: string(runes[idx])}
+}
+
+func (in *Interpreter) evalSliceExpression(
+	node *ast.SliceExpression,
+	env *object.Environment,
+) object.Object {
+	left := in.Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	var bounds [3]*int64
+	for i, exp := range []ast.Expression{node.Start, node.End, node.Step} {
+		if exp == nil {
+			continue
+		}
+		value := in.Eval(exp, env)
+		if isError(value) {
+			return value
+		}
+		integer, ok := value.(*object.Integer)
+		if !ok {
+			return newError("slice indices must be INTEGER, got %s", value.Type())
+		}
+		bounds[i] = &integer.Value
+	}
+
+	return sliceObject(left, bounds)
+}
+
+// sliceObject slices an ARRAY or a STRING by bounds, which are nil where
+// they were left out.
+func sliceObject(left object.Object, bounds [3]*int64) object.Object {
+	switch left := left.(type) {
+	case *object.Array:
+		start, stop, step, err := sliceIndices(int64(len(left.Elements)), bounds[0], bounds[1], bounds[2])
+		if err != nil {
+			return err
+		}
+		if step == 1 {
+			if stop < start {
+				stop = start
+			}
+			// Arrays are never mutated in place, so a plain slice can share
+			// the backing array; capping its capacity keeps a later append
+			// from writing into the original.
+			return &object.Array{Elements: left.Elements[start:stop:stop]}
+		}
+
+		elements := []object.Object{}
+		for i := start; (step > 0 && i < stop) || (step < 0 && i > stop); i += step {
+			elements = append(elements, left.Elements[i])
+		}
+		return &object.Array{Elements: elements}
+
+	case *object.String:
+		runes := []rune(left.Value)
+		start, stop, step, err := sliceIndices(int64(len(runes)), bounds[0], bounds[1], bounds[2])
+		if err != nil {
+			return err
+		}
+
+		var out []rune
+		for i := start; (step > 0 && i < stop) || (step < 0 && i > stop); i += step {
+			out = append(out, runes[i])
+		}
+		return &object.String{Value: string(out)}
+
+	default:
+		return newError("slice operator not supported: %s", left.Type())
+	}
+}
+
+// sliceIndices resolves optional slice bounds against a sequence of the
+// given length the way Python does: negative values count from the end
+// and out of range values are clamped instead of being errors.
+func sliceIndices(length int64, start, stop, step *int64) (int64, int64, int64, *object.Error) {
+	st := int64(1)
+	if step != nil {
+		st = *step
+	}
+	if st == 0 {
+		return 0, 0, 0, newError("slice step cannot be zero")
+	}
+
+	// With a negative step the walk runs from the end towards -1, which
+	// stands for "before the first element".
+	lower, upper := int64(0), length
+	if st < 0 {
+		lower, upper = -1, length-1
+	}
+
+	clamp := func(bound *int64, def int64) int64 {
+		if bound == nil {
+			return def
+		}
+		i := *bound
+		if i < 0 {
+			i += length
+			if i < lower {
+				i = lower
+			}
+		} else if i > upper {
+			i = upper
+		}
+		return i
+	}
+
+	if st > 0 {
+		return clamp(start, lower), clamp(stop, upper), st, nil
+	}
+	return clamp(start, upper), clamp(stop, lower), st, nil
+}
+
+func (in *Interpreter) evalHashLiteral(
 	node *ast.HashLiteral,
 	env *object.Environment,
 ) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+	hash := object.NewHash()
 
-	for keyNode, valueNode := range node.Pairs {
-		key := Eval(keyNode, env)
+	for _, keyNode := range node.Keys {
+		key := in.Eval(keyNode, env)
 		if isError(key) {
 			return key
 		}
 
-		hashKey, ok := key.(object.Hashable)
-		if !ok {
+		if !object.IsHashable(key) {
 			return newError("unusable as hash key: %s", key.Type())
 		}
 
-		value := Eval(valueNode, env)
+		value := in.Eval(node.Pairs[keyNode], env)
 		if isError(value) {
 			return value
 		}
 
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		hash.Set(key, value)
 	}
 
-	return &object.Hash{Pairs: pairs}
+	return hash
 }
 
 func evalHashIndexExpression(hash, index object.Object) object.Object {
 	hashObject := hash.(*object.Hash)
 
-	key, ok := index.(object.Hashable)
-	if !ok {
+	if !object.IsHashable(index) {
 		return newError("unusable as hash key: %s", index.Type())
 	}
 
-	pair, ok := hashObject.Pairs[key.HashKey()]
+	value, ok := hashObject.Get(index)
 	if !ok {
 		return NULL
 	}
 
-	return pair.Value
+	return value
+}
+
+func evalDotExpression(left object.Object, name string) object.Object {
+	host, ok := left.(object.HostObject)
+	if !ok {
+		return newError("property access not supported: %s", left.Type())
+	}
+
+	value, err := host.GetField(name)
+	if err != nil {
+		return newError("%s", err)
+	}
+
+	return value
+}
+
+// evalMethodCall calls a module member or host method. Module members are
+// builtins and go through apply, so a Hook sees them like any other call.
+func (in *Interpreter) evalMethodCall(
+	call *ast.CallExpression,
+	dot *ast.DotExpression,
+	env *object.Environment,
+) object.Object {
+	left := in.Eval(dot.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	host, ok := left.(object.HostObject)
+	if !ok {
+		return newError("property access not supported: %s", left.Type())
+	}
+
+	args := in.evalExpressions(call.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	name := dot.Property.Value
+	if module, ok := host.(*object.Module); ok {
+		if builtin, ok := module.Members[name].(*object.Builtin); ok {
+			return in.apply(call, env, builtin, args)
+		}
+	}
+	if in.Hook == nil {
+		return CallMethod(host, name, args)
+	}
+
+	method := &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		return CallMethod(host, name, args)
+	}}
+	return in.apply(call, env, method, args)
+}
+
+func (in *Interpreter) evalAssignExpression(
+	node *ast.AssignExpression,
+	env *object.Environment,
+) object.Object {
+	target, ok := node.Target.(*ast.DotExpression)
+	if !ok {
+		return newError("invalid assignment target: %s", node.Target)
+	}
+
+	left := in.Eval(target.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	host, ok := left.(object.HostObject)
+	if !ok {
+		return newError("property assignment not supported: %s", left.Type())
+	}
+
+	value := in.Eval(node.Value, env)
+	if isError(value) {
+		return value
+	}
+
+	return SetField(host, target.Property.Value, value)
 }
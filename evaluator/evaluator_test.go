@@ -1,9 +1,13 @@
 package evaluator
 
 import (
+	"bytes"
+	"cathon/ast"
 	"cathon/lexer"
 	"cathon/object"
 	"cathon/parser"
+	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -67,6 +71,92 @@ func TestEvalBooleanExpression(t *testing.T) {
 	}
 }
 
+func TestEqualityExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"[1, 2] == [1, 2]", true},
+		{"[1, 2] != [1, 2]", false},
+		{"[1, 2] == [2, 1]", false},
+		{"[1, [2, 3]] == [1, [2, 3]]", true},
+		{"[] == []", true},
+		{"[1] == [true]", false},
+		{`{"a": [1], "b": 2} == {"b": 2, "a": [1]}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`{"a": 1} == {"a": 1, "b": 2}`, false},
+		{"if (false) { 1 } == if (false) { 2 }", true},
+		{"let f = fn(x) { x }; f == f", true},
+		{"fn(x) { x } == fn(x) { x }", false},
+		{"len == len", true},
+		{"1 == true", false},
+		{"[1] == 1", false},
+	}
+
+	for _, tt := range tests {
+		evaluated := CheckEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestStringComparison(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`"a" == "a"`, true},
+		{`"a" == "b"`, false},
+		{`"a" != "b"`, true},
+		{`"a" != "a"`, false},
+		{`"apple" < "banana"`, true},
+		{`"apple" > "banana"`, false},
+		{`"ab" < "abc"`, true},
+		{`"b" <= "b"`, true},
+		{`"c" <= "b"`, false},
+		{`"b" >= "a"`, true},
+		{`"a" >= "b"`, false},
+		{`"" < "a"`, true},
+		{`"cat" in "concatenate"`, true},
+		{`"dog" in "concatenate"`, false},
+		{`"" in "cat"`, true},
+		{`"Cat" + "Game" == "CatGame"`, true},
+		{"1 <= 1", true},
+		{"2 <= 1", false},
+		{"1 >= 2", false},
+		{"2 >= 2", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := CheckEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestStringRepetition(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"ab" * 3`, "ababab"},
+		{`3 * "ab"`, "ababab"},
+		{`"ab" * 0`, ""},
+		{`"ab" * -2`, ""},
+		{`"-" * 2 + "|"`, "--|"},
+	}
+
+	for _, tt := range tests {
+		evaluated := CheckEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. got=%q, want=%q", str.Value, tt.expected)
+		}
+	}
+}
+
 func TestBangOperator(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -193,6 +283,22 @@ func TestErrorHandling(t *testing.T) {
 			`"Hello" - "World"`,
 			"unknown operator: STRING - STRING",
 		},
+		{
+			`"Hello" * "World"`,
+			"unknown operator: STRING * STRING",
+		},
+		{
+			`"a" * 1000000000000`,
+			"string repetition too long: 1 * 1000000000000",
+		},
+		{
+			`1 in "one"`,
+			"type mismatch: INTEGER in STRING",
+		},
+		{
+			`"ab" - 1`,
+			"type mismatch: STRING - INTEGER",
+		},
 		{
 			"if (10 > 1) { true + false; }",
 			"unknown operator: BOOLEAN + BOOLEAN",
@@ -217,10 +323,18 @@ if (10 > 1) {
 			`{"name": "Monkey"}[fn(x) { x }];`,
 			"unusable as hash key: FUNCTION",
 		},
+		{
+			`{[1, fn(x) { x }]: 1}`,
+			"unusable as hash key: ARRAY",
+		},
 		{
 			`999[1]`,
 			"index operator not supported: INTEGER",
 		},
+		{
+			"let add = fn(x, y) { x + y; }; add(1);",
+			"wrong number of arguments. got=1, want=2",
+		},
 	}
 
 	for _, tt := range tests {
@@ -364,6 +478,7 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("")`, 0},
 		{`len("four")`, 4},
 		{`len("hello world")`, 11},
+		{`len("고양이")`, 3},
 		{`len(1)`, "argument to `len` not supported, got INTEGER"},
 		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
 		{`len([1, 2, 3])`, 3},
@@ -379,6 +494,11 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`rest([])`, nil},
 		{`push([], 1)`, []int{1}},
 		{`push(1, 1)`, "argument to `push` must be ARRAY, got INTEGER"},
+		{`bytes("a고")`, []int{97, 234, 179, 160}},
+		{`bytes("")`, []int{}},
+		{`bytes(1)`, "argument to `bytes` must be STRING, got INTEGER"},
+		{`runes("a고")`, []int{97, 44256}},
+		{`runes([])`, "argument to `runes` must be STRING, got ARRAY"},
 	}
 
 	for _, tt := range tests {
@@ -420,6 +540,86 @@ func TestBuiltinFunctions(t *testing.T) {
 	}
 }
 
+func TestHigherOrderBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`map([1, 2, 3], fn(x) { x * 2 })`, []int{2, 4, 6}},
+		{`map([], fn(x) { x * 2 })`, []int{}},
+		{`map([[1], [1, 2]], len)`, []int{1, 2}},
+		{`let offset = 10; map([1, 2], fn(x) { x + offset })`, []int{11, 12}},
+		{`filter([1, 2, 3, 4], fn(x) { x > 2 })`, []int{3, 4}},
+		{`reduce([1, 2, 3, 4], fn(acc, x) { acc + x })`, 10},
+		{`reduce([1, 2, 3], fn(acc, x) { acc + x }, 10)`, 16},
+		{`reduce([], fn(acc, x) { acc + x }, 0)`, 0},
+		{`sort([3, 1, 2])`, []int{1, 2, 3}},
+		{`sort([3, 1, 2], fn(a, b) { a > b })`, []int{3, 2, 1}},
+		{`let a = [3, 1, 2]; sort(a); a`, []int{3, 1, 2}},
+		{`map(sort([[2, 0], [1, 1], [2, 2], [1, 3]], fn(a, b) { a[0] < b[0] }), last)`, []int{1, 3, 0, 2}},
+		{`find([1, 2, 3, 4], fn(x) { x > 2 })`, 3},
+		{`find([1, 2], fn(x) { x > 2 })`, nil},
+		{`any([1, 2, 3], fn(x) { x == 2 })`, true},
+		{`any([], fn(x) { true })`, false},
+		{`all([1, 2, 3], fn(x) { x > 0 })`, true},
+		{`all([1, 2, 3], fn(x) { x > 1 })`, false},
+		{`map(zip([1, 2, 3], [10, 20]), fn(p) { p[0] + p[1] })`, []int{11, 22}},
+		{`zip()`, "wrong number of arguments. got=0, want at least 1"},
+		{`zip([1], 2)`, "argument to `zip` must be ARRAY, got INTEGER"},
+		{`map(1, fn(x) { x })`, "argument to `map` must be ARRAY, got INTEGER"},
+		{`filter([1], 1)`, "argument to `filter` must be FUNCTION, got INTEGER"},
+		{`map([1])`, "wrong number of arguments. got=1, want=2"},
+		{`map([1, 2], fn(x) { x + true })`, "type mismatch: INTEGER + BOOLEAN"},
+		{`map([1, 2], fn(x, y) { x })`, "wrong number of arguments. got=1, want=2"},
+		{`filter([1], fn(x) { y })`, "identifier not found: y"},
+		{`reduce([], fn(acc, x) { acc + x })`, "`reduce` of empty ARRAY with no initial value"},
+		{`reduce([1, 2], fn(acc, x) { acc + true })`, "type mismatch: INTEGER + BOOLEAN"},
+		{`sort([1, "a"])`, "`sort` cannot compare STRING and INTEGER"},
+		{`sort([1, 2], fn(a, b) { a - b })`, "comparator to `sort` must return BOOLEAN, got INTEGER"},
+		{`sort([1, 2], fn(a, b) { a + true })`, "type mismatch: INTEGER + BOOLEAN"},
+		{`any([1], fn(x) { -true })`, "unknown operator: -BOOLEAN"},
+		{`all([1], fn(x) { -true })`, "unknown operator: -BOOLEAN"},
+		{`find([1], fn(x) { -true })`, "unknown operator: -BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		evaluated := CheckEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q",
+					expected, errObj.Message)
+			}
+		case []int:
+			array, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("obj not Array. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if len(array.Elements) != len(expected) {
+				t.Errorf("wrong num of elements. want=%d, got=%d",
+					len(expected), len(array.Elements))
+				continue
+			}
+			for i, expectedElem := range expected {
+				testIntegerObject(t, array.Elements[i], int64(expectedElem))
+			}
+		}
+	}
+}
+
 func TestArrayLiterals(t *testing.T) {
 	input := "[1, 2 * 2, 3 + 3]"
 
@@ -497,6 +697,114 @@ func TestArrayIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestStringIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"cat"[0]`, "c"},
+		{`"cat"[2]`, "t"},
+		{`"cat"[3]`, nil},
+		{`"cat"[-1]`, "t"},
+		{`"cat"[-3]`, "c"},
+		{`"cat"[-4]`, nil},
+		{`"고양이"[1]`, "양"},
+		{`"고양이"[-1]`, "이"},
+		{`let s = "나비"; s[len(s) - 1]`, "비"},
+		{`""[0]`, nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := CheckEval(tt.input)
+		expected, ok := tt.expected.(string)
+		if !ok {
+			testNullObject(t, evaluated)
+			continue
+		}
+
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if str.Value != expected {
+			t.Errorf("String has wrong value. got=%q, want=%q", str.Value, expected)
+		}
+	}
+}
+
+func TestSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[1, 2, 3, 4][1:3]", []int{2, 3}},
+		{"[1, 2, 3, 4][:2]", []int{1, 2}},
+		{"[1, 2, 3, 4][-2:]", []int{3, 4}},
+		{"[1, 2, 3, 4][:]", []int{1, 2, 3, 4}},
+		{"[1, 2, 3, 4][::2]", []int{1, 3}},
+		{"[1, 2, 3, 4][1::2]", []int{2, 4}},
+		{"[1, 2, 3, 4][::-1]", []int{4, 3, 2, 1}},
+		{"[1, 2, 3, 4][2::-1]", []int{3, 2, 1}},
+		{"[1, 2, 3, 4][:0:-1]", []int{4, 3, 2}},
+		{"[1, 2, 3, 4][-100:100]", []int{1, 2, 3, 4}},
+		{"[1, 2, 3, 4][3:1]", []int{}},
+		{"[1, 2, 3, 4][10:]", []int{}},
+		{"[1, 2, 3, 4][-100:-50]", []int{}},
+		{"[][::-1]", []int{}},
+		{"let a = [1, 2, 3]; let b = a[:2]; push(b, 9); a", []int{1, 2, 3}},
+		{"let a = [1, 2, 3]; push(a[:2], 9)", []int{1, 2, 9}},
+		{`"고양이 게임"[:3]`, "고양이"},
+		{`"hello"[1:-1]`, "ell"},
+		{`"hello"[::-1]`, "olleh"},
+		{`"hello"[::2]`, "hlo"},
+		{`"hello"[10:]`, ""},
+		{"[1, 2][::0]", fmt.Errorf("slice step cannot be zero")},
+		{`[1, 2]["a":]`, fmt.Errorf("slice indices must be INTEGER, got STRING")},
+		{"5[1:]", fmt.Errorf("slice operator not supported: INTEGER")},
+	}
+
+	for _, tt := range tests {
+		evaluated := CheckEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case []int:
+			array, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("obj not Array. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if len(array.Elements) != len(expected) {
+				t.Errorf("wrong num of elements for %q. want=%d, got=%d",
+					tt.input, len(expected), len(array.Elements))
+				continue
+			}
+			for i, expectedElem := range expected {
+				testIntegerObject(t, array.Elements[i], int64(expectedElem))
+			}
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value. got=%q, want=%q", str.Value, expected)
+			}
+		case error:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected.Error() {
+				t.Errorf("wrong error message. expected=%q, got=%q",
+					expected.Error(), errObj.Message)
+			}
+		}
+	}
+}
+
 func TestHashLiterals(t *testing.T) {
 	input := `let two = "two";
 	{
@@ -514,26 +822,455 @@ func TestHashLiterals(t *testing.T) {
 		t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
 	}
 
-	expected := map[object.HashKey]int64{
-		(&object.String{Value: "one"}).HashKey():   1,
-		(&object.String{Value: "two"}).HashKey():   2,
-		(&object.String{Value: "three"}).HashKey(): 3,
-		(&object.Integer{Value: 4}).HashKey():      4,
-		TRUE.HashKey():                             5,
-		FALSE.HashKey():                            6,
+	expected := []struct {
+		key   object.Object
+		value int64
+	}{
+		{&object.String{Value: "one"}, 1},
+		{&object.String{Value: "two"}, 2},
+		{&object.String{Value: "three"}, 3},
+		{&object.Integer{Value: 4}, 4},
+		{TRUE, 5},
+		{FALSE, 6},
 	}
 
-	if len(result.Pairs) != len(expected) {
-		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+	if result.Len() != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", result.Len())
 	}
 
-	for expectedKey, expectedValue := range expected {
-		pair, ok := result.Pairs[expectedKey]
+	for i, pair := range result.Pairs() {
+		if !pair.Key.Equals(expected[i].key) {
+			t.Errorf("pair %d has wrong key. want=%s, got=%s",
+				i, expected[i].key.Inspect(), pair.Key.Inspect())
+		}
+
+		value, ok := result.Get(expected[i].key)
 		if !ok {
 			t.Errorf("no pair for given key in Pairs")
+			continue
+		}
+
+		testIntegerObject(t, value, expected[i].value)
+	}
+}
+
+func TestHashBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`keys({"b": 2, "a": 1, "c": 3})`, `[b, a, c]`},
+		{`keys({2: "b", 1: "a", true: 0, "x": 0})`, `[2, 1, true, x]`},
+		{`keys({})`, `[]`},
+		{`values({"b": 2, "a": 1, "c": 3})`, `[2, 1, 3]`},
+		{`entries({"b": 2, "a": 1})`, `[[b, 2], [a, 1]]`},
+		{`has({"a": 1}, "a")`, `true`},
+		{`has({"a": 1}, "b")`, `false`},
+		{`delete({"a": 1, "b": 2}, "a")`, `{b: 2}`},
+		{`delete({"a": 1}, "b")`, `{a: 1}`},
+		{`let h = {"a": 1}; delete(h, "a"); h`, `{a: 1}`},
+		{`merge({"a": 1, "b": 2}, {"c": 4, "b": 3})`, `{a: 1, b: 3, c: 4}`},
+		{`delete({"a": 1, "b": 2, "c": 3}, "b")`, `{a: 1, c: 3}`},
+		{`{"z": 1, "y": 2, "x": 3, "z": 4}`, `{z: 4, y: 2, x: 3}`},
+		{`let h = {"a": 1}; merge(h, {"a": 2}); h`, `{a: 1}`},
+		{`keys(merge({"a": 1}, {"b": 2}, {"c": 3}))`, `[a, b, c]`},
+		{`keys([1])`, "ERROR: argument to `keys` must be HASH, got ARRAY"},
+		{`has({}, fn(x) { x })`, "ERROR: unusable as hash key: FUNCTION"},
+		{`delete({})`, "ERROR: wrong number of arguments. got=1, want=2"},
+		{`merge({}, 1)`, "ERROR: argument to `merge` must be HASH, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := CheckEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestStringModule(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`string.split("a,b,,c", ",")`, `[a, b, , c]`},
+		{`string.split("  cat  game ")`, `[cat, game]`},
+		{`string.split("고양이", "")`, `[고, 양, 이]`},
+		{`string.join(["a", "b", "c"], "-")`, `a-b-c`},
+		{`string.join([], "-")`, ``},
+		{"string.trim(\"  나비 \t\n\")", `나비`},
+		{`string.trim("--cat--", "-")`, `cat`},
+		{`string.upper("Café")`, `CAFÉ`},
+		{`string.lower("ÀÉÎ Cat")`, `àéî cat`},
+		{`string.replace("a cat, a hat", "a ", "the ")`, `the cat, the hat`},
+		{`string.contains("concatenate", "cat")`, `true`},
+		{`string.contains("concatenate", "dog")`, `false`},
+		{`string.startsWith("고양이", "고")`, `true`},
+		{`string.endsWith("고양이", "양")`, `false`},
+		{`string.indexOf("고양이 게임", "게임")`, `4`},
+		{`string.indexOf("cat", "dog")`, `-1`},
+		{`string.repeat("야옹", 2)`, `야옹야옹`},
+		{`string.padLeft("7", 3, "0")`, `007`},
+		{`string.padLeft("고양이", 5)`, `  고양이`},
+		{`string.padRight("ab", 7, "xy")`, `abxyxyx`},
+		{`string.padRight("long", 2)`, `long`},
+		{`let up = string.upper; up("a")`, `A`},
+		{`string`, `<module string>`},
+		{`string.split(1, ",")`, "ERROR: argument to `split` must be STRING, got INTEGER"},
+		{`string.split("a", ",", 1)`, "ERROR: wrong number of arguments. got=3, want=1 or 2"},
+		{`string.join(["a", 1], "")`, "ERROR: argument to `join` must be ARRAY of STRING, got INTEGER element"},
+		{`string.upper()`, "ERROR: wrong number of arguments. got=0, want=1"},
+		{`string.padLeft("a", "3")`, "ERROR: argument to `padLeft` must be INTEGER, got STRING"},
+		{`string.padLeft("a", 3, "")`, "ERROR: padding for `padLeft` must not be empty"},
+		{`string.title("a")`, "ERROR: module string has no member title"},
+		{`string.upper = 1`, "ERROR: cannot assign to module member string.upper"},
+	}
+
+	for _, tt := range tests {
+		evaluated := CheckEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestFloatExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1.5", "1.5"},
+		{"-2.5", "-2.5"},
+		{"0.5 + 0.25", "0.75"},
+		{"1 + 0.5", "1.5"},
+		{"3.0 * 2", "6.0"},
+		{"7 / 2.0", "3.5"},
+		{"1.0 / 0", "+Inf"},
+		{"0.1 < 1", "true"},
+		{"2 >= 2.0", "true"},
+		{"2 == 2.0", "true"},
+		{"2.5 != 2.5", "false"},
+		{"[1, 2] == [1.0, 2]", "true"},
+		{"[1, 2] == [1, 2.5]", "false"},
+		{`{1: "a"}[1.0]`, "a"},
+		{`{1.0: "a", 1: "b"}`, "{1: b}"},
+		{`{0: "z"}[-0.0]`, "z"},
+		{`{1.5: "a"}[1]`, "null"},
+		{"sort([2.5, 1, 1.5])", "[1, 1.5, 2.5]"},
+		{"1 / 0", "ERROR: division by zero"},
+		{"2.5 - true", "ERROR: type mismatch: FLOAT - BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		evaluated := CheckEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestMathModule(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`math.abs(-3)`, `3`},
+		{`math.abs(-2.5)`, `2.5`},
+		{`math.min(3, 1.5, 2)`, `1.5`},
+		{`math.max(3, 1.5, 2)`, `3`},
+		{`math.max(4)`, `4`},
+		{`math.clamp(15, 0, 10)`, `10`},
+		{`math.clamp(-1, 0, 10)`, `0`},
+		{`math.clamp(0.5, 0, 1)`, `0.5`},
+		{`math.floor(2.7)`, `2`},
+		{`math.floor(-2.5)`, `-3`},
+		{`math.ceil(2.1)`, `3`},
+		{`math.round(2.5)`, `3`},
+		{`math.floor(5)`, `5`},
+		{`math.sqrt(16)`, `4.0`},
+		{`math.sin(0)`, `0.0`},
+		{`math.cos(0)`, `1.0`},
+		{`math.atan2(1, 1) * 4 == math.pi`, `true`},
+		{`math.lerp(10, 20, 0.25)`, `12.5`},
+		{`math.min()`, "ERROR: wrong number of arguments. got=0, want at least 1"},
+		{`math.abs("a")`, "ERROR: argument to `abs` must be INTEGER or FLOAT, got STRING"},
+		{`math.clamp(1, 10, 0)`, "ERROR: bounds for `clamp` out of order: 10 > 0"},
+		{`math.sqrt(-1)`, "ERROR: argument to `sqrt` must not be negative, got -1"},
+		{`math.abs(-9223372036854775807 - 1)`, "ERROR: result of `abs` out of INTEGER range: 9223372036854775808"},
+		{`math.floor(1.0 / 0)`, "ERROR: result of `floor` out of INTEGER range: +Inf"},
+		{`math.random(0)`, "ERROR: argument to `random` must be positive, got 0"},
+		{`math.random(3, 1)`, "ERROR: bounds for `random` out of order: 3 > 1"},
+		{`math.random(1, 2, 3)`, "ERROR: wrong number of arguments. got=3, want=0, 1 or 2"},
+		{`math.seed("a")`, "ERROR: argument to `seed` must be INTEGER, got STRING"},
+	}
+
+	for _, tt := range tests {
+		evaluated := CheckEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestMathRandom(t *testing.T) {
+	input := `[math.random(), math.random(100), math.random(-5, 5), math.random(7, 7)]`
+	run := func(in *Interpreter) object.Object {
+		l := lexer.New(input)
+		p := parser.New(l)
+		return in.Eval(p.ParseProgram(), object.NewEnvironment())
+	}
+
+	first := run(New())
+	arr, ok := first.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", first, first)
+	}
+	if f := arr.Elements[0].(*object.Float).Value; f < 0 || f >= 1 {
+		t.Errorf("math.random() out of range: %f", f)
+	}
+	if n := arr.Elements[1].(*object.Integer).Value; n < 0 || n >= 100 {
+		t.Errorf("math.random(100) out of range: %d", n)
+	}
+	if n := arr.Elements[2].(*object.Integer).Value; n < -5 || n > 5 {
+		t.Errorf("math.random(-5, 5) out of range: %d", n)
+	}
+	testIntegerObject(t, arr.Elements[3], 7)
+
+	if again := run(New()); !again.Equals(first) {
+		t.Errorf("new interpreters don't repeat. got=%s, want=%s",
+			again.Inspect(), first.Inspect())
+	}
+
+	in := New()
+	in.Seed(42)
+	seeded := run(in)
+	if seeded.Equals(first) {
+		t.Errorf("Seed didn't change the numbers: %s", seeded.Inspect())
+	}
+
+	l := lexer.New("math.seed(42); " + input)
+	p := parser.New(l)
+	if fromScript := New().Eval(p.ParseProgram(), object.NewEnvironment()); !fromScript.Equals(seeded) {
+		t.Errorf("math.seed differs from Seed. got=%s, want=%s",
+			fromScript.Inspect(), seeded.Inspect())
+	}
+
+	state, err := in.RandomState()
+	if err != nil {
+		t.Fatalf("RandomState failed: %s", err)
+	}
+	want := run(in)
+
+	restored := New()
+	if err := restored.SetRandomState(state); err != nil {
+		t.Fatalf("SetRandomState failed: %s", err)
+	}
+	if got := run(restored); !got.Equals(want) {
+		t.Errorf("restored state doesn't replay. got=%s, want=%s",
+			got.Inspect(), want.Inspect())
+	}
+}
+
+func TestTypeBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`type(1)`, `INTEGER`},
+		{`type(1.5)`, `FLOAT`},
+		{`type("a")`, `STRING`},
+		{`type(if (false) { 1 })`, `NULL`},
+		{`type(fn(x) { x })`, `FUNCTION`},
+		{`type(len)`, `BUILTIN`},
+		{`type(math)`, `MODULE`},
+		{`"HP: " + str(42)`, `HP: 42`},
+		{`str([1, "a"])`, `[1, a]`},
+		{`str(2.0)`, `2.0`},
+		{`int("-17")`, `-17`},
+		{`int(2.9)`, `2`},
+		{`int(-2.9)`, `-2`},
+		{`int(true)`, `1`},
+		{`int(7)`, `7`},
+		{`float(3)`, `3.0`},
+		{`float("0.25")`, `0.25`},
+		{`bool(0)`, `true`},
+		{`bool("")`, `true`},
+		{`bool(if (false) { 1 })`, `false`},
+		{`bool(false)`, `false`},
+		{`isInt(1)`, `true`},
+		{`isInt(1.0)`, `false`},
+		{`isNumber(1.0)`, `true`},
+		{`isString("a")`, `true`},
+		{`isArray({})`, `false`},
+		{`isHash({})`, `true`},
+		{`isNull(puts)`, `false`},
+		{`isFunction(puts)`, `true`},
+		{`isFunction(fn() {})`, `true`},
+		{`int("12abc")`, "ERROR: could not parse \"12abc\" as INTEGER"},
+		{`float("x")`, "ERROR: could not parse \"x\" as FLOAT"},
+		{`int(1.0 / 0)`, "ERROR: +Inf out of INTEGER range"},
+		{`int([1])`, "ERROR: cannot convert ARRAY to INTEGER"},
+		{`float(true)`, "ERROR: cannot convert BOOLEAN to FLOAT"},
+		{`str(1, 2)`, "ERROR: wrong number of arguments. got=2, want=1"},
+		{`type()`, "ERROR: wrong number of arguments. got=0, want=1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := CheckEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestSprintf(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`sprintf("HP: %d/%d", 7, 10)`, `HP: 7/10`},
+		{`sprintf("%v and %v", [1, "a"], {"k": true})`, `[1, a] and {k: true}`},
+		{`sprintf("%s!", "고양이")`, `고양이!`},
+		{`sprintf("[%5s]", "고양이")`, `[  고양이]`},
+		{`sprintf("[%-5s]", "ab")`, `[ab   ]`},
+		{`sprintf("%.2s", "고양이")`, `고양`},
+		{`sprintf("%q", "cat")`, `"cat"`},
+		{"sprintf(\"%q\", \"a\tb\")", `"a\tb"`},
+		{`sprintf("%05d", 42)`, `00042`},
+		{`sprintf("%+d", 3)`, `+3`},
+		{`sprintf("%x", 255)`, `ff`},
+		{`sprintf("%x", "hi")`, `6869`},
+		{`sprintf("%.2f", 3.14159)`, `3.14`},
+		{`sprintf("%8.3f|", 2)`, `   2.000|`},
+		{`sprintf("%e", 1500.0)`, `1.500000e+03`},
+		{`sprintf("%g", 0.5)`, `0.5`},
+		{`sprintf("%t", 1 < 2)`, `true`},
+		{`sprintf("100%%")`, `100%`},
+		{`sprintf("no verbs")`, `no verbs`},
+		{`sprintf("%d")`, "ERROR: not enough arguments for format \"%d\""},
+		{`sprintf("%d", 1, 2)`, "ERROR: too many arguments for format \"%d\". got=2, want=1"},
+		{`sprintf("%d", "a")`, "ERROR: format verb %d can't format STRING"},
+		{`sprintf("%q", 1)`, "ERROR: format verb %q can't format INTEGER"},
+		{`sprintf("%y", 1)`, "ERROR: unknown format verb %y"},
+		{`sprintf("50%")`, "ERROR: incomplete verb at end of format \"50%\""},
+		{`sprintf(1)`, "ERROR: argument to `sprintf` must be STRING, got INTEGER"},
+		{`sprintf()`, "ERROR: wrong number of arguments. got=0, want at least 1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := CheckEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestJSONModule(t *testing.T) {
+	// Cathon strings can't hold double quotes, so the documents are bound
+	// to doc instead of being written inline.
+	tests := []struct {
+		doc      string
+		input    string
+		expected string
+	}{
+		{`[1, 2.5, true, null, "a"]`, `json.parse(doc)`, `[1, 2.5, true, null, a]`},
+		{`{"b": 1, "a": {"c": []}}`, `json.parse(doc)`, `{b: 1, a: {c: []}}`},
+		{`{"z": 1, "y": 2, "x": 3}`, `keys(json.parse(doc))`, `[z, y, x]`},
+		{`1e3`, `json.parse(doc)`, `1000.0`},
+		{`12345678901234567890`, `json.parse(doc)`, `1.2345678901234567e+19`},
+		{` "\u00e9" `, `json.parse(doc)`, `é`},
+		{`{"x":[1,"y"],"z":{"w":null}}`, `json.stringify(json.parse(doc)) == doc`, `true`},
+		{``, `json.stringify({"name": "Nabi", "lives": 9, "tags": ["cat", true]})`,
+			`{"name":"Nabi","lives":9,"tags":["cat",true]}`},
+		{``, `json.stringify([1.0, 0.5, false])`, `[1.0,0.5,false]`},
+		{``, `json.stringify("<a & b>")`, `"<a & b>"`},
+		{``, `json.stringify({"a": [1], "b": {}}, 2)`, "{\n  \"a\": [\n    1\n  ],\n  \"b\": {}\n}"},
+		{``, "json.stringify([1], \"\t\")", "[\n\t1\n]"},
+		{``, `let a = [1, 2]; json.stringify([a, a])`, `[[1,2],[1,2]]`},
+		{`[1, 2`, `json.parse(doc)`, "ERROR: invalid JSON: unexpected end of JSON input"},
+		{`[1] 2`, `json.parse(doc)`, "ERROR: invalid JSON: unexpected data after value"},
+		{``, `json.parse(doc)`, "ERROR: invalid JSON: unexpected end of JSON input"},
+		{``, `json.parse(1)`, "ERROR: argument to `parse` must be STRING, got INTEGER"},
+		{``, `json.stringify(fn(x) { x })`, "ERROR: cannot convert FUNCTION to JSON"},
+		{``, `json.stringify([len])`, "ERROR: cannot convert BUILTIN to JSON"},
+		{``, `json.stringify({1: "a"})`, "ERROR: JSON object keys must be STRING, got INTEGER"},
+		{``, `json.stringify(1.0 / 0)`, "ERROR: cannot convert +Inf to JSON"},
+		{``, `json.stringify(1, 11)`, "ERROR: indent for `stringify` must be between 0 and 10, got 11"},
+		{``, `json.stringify(1, true)`, "ERROR: argument to `stringify` must be INTEGER or STRING, got BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		env.Set("doc", &object.String{Value: tt.doc})
+
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		evaluated := Eval(p.ParseProgram(), env)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestJSONRejectsCycles(t *testing.T) {
+	arr := &object.Array{}
+	arr.Elements = []object.Object{arr}
+	hash := object.NewHash()
+	hash.Set(&object.String{Value: "self"}, hash)
+
+	tests := []struct {
+		value    object.Object
+		expected string
+	}{
+		{arr, "cannot convert cyclic ARRAY to JSON"},
+		{hash, "cannot convert cyclic HASH to JSON"},
+	}
+
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		env.Set("value", tt.value)
+
+		l := lexer.New("json.stringify(value)")
+		p := parser.New(l)
+		evaluated := Eval(p.ParseProgram(), env)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+			continue
 		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q",
+				tt.expected, errObj.Message)
+		}
+	}
+}
+
+func TestOutputWriter(t *testing.T) {
+	var out bytes.Buffer
+	in := New()
+	in.Out = &out
 
-		testIntegerObject(t, pair.Value, expectedValue)
+	l := lexer.New("puts(\"a\", 1); printf(\"%s=%d\n\", \"hp\", 3); printf(\"%d\", \"x\")")
+	p := parser.New(l)
+	result := in.Eval(p.ParseProgram(), object.NewEnvironment())
+
+	if out.String() != "a\n1\nhp=3\n" {
+		t.Errorf("wrong output. got=%q", out.String())
+	}
+	errObj, ok := result.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", result, result)
+	}
+	if errObj.Message != "format verb %d can't format STRING" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
 	}
 }
 
@@ -570,18 +1307,146 @@ func TestHashIndexExpressions(t *testing.T) {
 			`{false: 5}[false]`,
 			5,
 		},
+		{
+			`{[1, 2]: 5}[[1, 2]]`,
+			5,
+		},
+		{
+			`{[1, [2, "a"]]: 5}[[1, [2, "a"]]]`,
+			5,
+		},
+		{
+			`{[1, 2]: 5}[[2, 1]]`,
+			nil,
+		},
+		{
+			`let pos = [3, 4]; let grid = {pos: "cat"}; has(grid, [3, 4])`,
+			true,
+		},
 	}
 
 	for _, tt := range tests {
 		evaluated := CheckEval(tt.input)
-		integer, ok := tt.expected.(int)
-		if ok {
-			testIntegerObject(t, evaluated, int64(integer))
-		} else {
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		default:
 			testNullObject(t, evaluated)
 		}
 	}
 }
+
+type testCat struct {
+	Name  string
+	X     int
+	Jumps []int
+}
+
+func (c *testCat) Jump(height int) int {
+	c.Jumps = append(c.Jumps, height)
+	return len(c.Jumps)
+}
+
+func (c *testCat) Rename(name string) error {
+	if name == "" {
+		return fmt.Errorf("name must not be empty")
+	}
+	c.Name = name
+	return nil
+}
+
+func TestHostObjects(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`cat.name`, "Nabi"},
+		{`cat.x + 1`, 4},
+		{`cat.x = 10; cat.x`, 10},
+		{`cat.x = cat.x * 2`, 6},
+		{`cat.jump(3); cat.jump(5)`, 2},
+		{`cat.jump(3); cat.jumps`, []int{3}},
+		{`cat.rename("Yaong"); cat.name`, "Yaong"},
+		{`cat.rename("")`, fmt.Errorf("name must not be empty")},
+		{`cat.age`, fmt.Errorf("<testCat> has no field age")},
+		{`cat.x = "far"`, fmt.Errorf("cannot assign STRING to <testCat>.x: want int, got STRING")},
+		{`cat.sleep()`, fmt.Errorf("<testCat> has no method sleep")},
+		{`cat.jump()`, fmt.Errorf("wrong number of arguments to jump. got=0, want=1")},
+		{`5.x`, fmt.Errorf("property access not supported: INTEGER")},
+		{`let n = 5; n.x = 1`, fmt.Errorf("property assignment not supported: INTEGER")},
+	}
+
+	for _, tt := range tests {
+		cat := &testCat{Name: "Nabi", X: 3}
+		env := object.NewEnvironment()
+		env.Set("cat", object.NewHost(cat))
+
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		evaluated := Eval(p.ParseProgram(), env)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value. got=%q, want=%q", str.Value, expected)
+			}
+		case []int:
+			array, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("obj not Array. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if len(array.Elements) != len(expected) {
+				t.Errorf("wrong num of elements. want=%d, got=%d",
+					len(expected), len(array.Elements))
+				continue
+			}
+			for i, expectedElem := range expected {
+				testIntegerObject(t, array.Elements[i], int64(expectedElem))
+			}
+		case error:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected.Error() {
+				t.Errorf("wrong error message. expected=%q, got=%q",
+					expected.Error(), errObj.Message)
+			}
+		}
+	}
+}
+
+func TestHostObjectWritesThrough(t *testing.T) {
+	cat := &testCat{Name: "Nabi"}
+	env := object.NewEnvironment()
+	env.Set("cat", object.NewHost(cat))
+
+	l := lexer.New(`cat.x = 10; cat.name = "Yaong"; cat.jump(2);`)
+	p := parser.New(l)
+	Eval(p.ParseProgram(), env)
+
+	if cat.X != 10 {
+		t.Errorf("cat.X has wrong value. got=%d, want=10", cat.X)
+	}
+	if cat.Name != "Yaong" {
+		t.Errorf("cat.Name has wrong value. got=%q, want=%q", cat.Name, "Yaong")
+	}
+	if len(cat.Jumps) != 1 || cat.Jumps[0] != 2 {
+		t.Errorf("cat.Jumps has wrong value. got=%v", cat.Jumps)
+	}
+}
+
 func CheckEval(input string) object.Object {
 	l := lexer.New(input)
 	p := parser.New(l)
@@ -627,3 +1492,73 @@ func testNullObject(t *testing.T, obj object.Object) bool {
 	}
 	return true
 }
+
+// recordingHook writes down the events it is told about.
+type recordingHook struct {
+	events []string
+}
+
+func (h *recordingHook) Statement(stmt ast.Statement, pos Position, env *object.Environment) {
+	h.events = append(h.events, fmt.Sprintf("stmt %d:%d %s", pos.Line, pos.Column, stmt.String()))
+}
+
+func (h *recordingHook) Call(call *ast.CallExpression, fn object.Object, pos Position, env *object.Environment) {
+	name := "-"
+	if call != nil {
+		name = call.Function.String()
+	}
+	h.events = append(h.events, fmt.Sprintf("call %d:%d %s %v", pos.Line, pos.Column, name, env.LocalNames()))
+}
+
+func (h *recordingHook) Return(fn object.Object, result object.Object) {
+	h.events = append(h.events, "return "+result.Inspect())
+}
+
+func TestHook(t *testing.T) {
+	input := `let double = fn(x) {
+  x * 2
+};
+map([1], double);
+string.upper("a");
+cat.jump(2);
+double(len("ab"))`
+
+	hook := &recordingHook{}
+	in := New()
+	in.Hook = hook
+	env := object.NewEnvironment()
+	env.Set("cat", object.NewHost(&testCat{Name: "Nabi"}))
+	result := in.Eval(parser.New(lexer.New(input)).ParseProgram(), env)
+	testIntegerObject(t, result, 4)
+
+	expected := []string{
+		"stmt 1:1 let double = fn(x) (x * 2);",
+		"stmt 4:1 map([1], double)",
+		"call 4:1 map [cat double]",
+		"call 1:20 - [x]",
+		"stmt 2:3 (x * 2)",
+		"return 2",
+		"return [2]",
+		"stmt 5:1 (string.upper)(a)",
+		"call 5:8 (string.upper) [cat double]",
+		"return A",
+		"stmt 6:1 (cat.jump)(2)",
+		"call 6:5 (cat.jump) [cat double]",
+		"return 1",
+		"stmt 7:1 double(len(ab))",
+		"call 7:8 len [cat double]",
+		"return 2",
+		"call 7:1 double [x]",
+		"stmt 2:3 (x * 2)",
+		"return 4",
+	}
+	if len(hook.events) != len(expected) {
+		t.Fatalf("wrong number of events. expected=%d, got=%d:\n%s",
+			len(expected), len(hook.events), strings.Join(hook.events, "\n"))
+	}
+	for i, want := range expected {
+		if hook.events[i] != want {
+			t.Errorf("wrong event %d. expected=%q, got=%q", i, want, hook.events[i])
+		}
+	}
+}
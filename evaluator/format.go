@@ -0,0 +1,144 @@
+package evaluator
+
+import (
+	"cathon/object"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// format implements sprintf and printf. A verb is written
+//
+//	%[flags][width][.precision]verb
+//
+// where the flags are '-' (pad on the right), '0' (pad numbers with
+// zeros), '+' (always print a sign) and ' ' (leave a space for the sign),
+// and width and precision are decimal numbers. The verbs are
+//
+//	%v  any value, as puts shows it
+//	%s  the same as %v; precision cuts it to that many characters
+//	%q  a STRING, double-quoted with escapes
+//	%d  an INTEGER in decimal
+//	%x  an INTEGER in hexadecimal, or a STRING as hex bytes
+//	%f  a number without an exponent; precision is digits after the point
+//	%e  a number with an exponent
+//	%g  a number with an exponent only when it is large or small
+//	%t  a BOOLEAN
+//	%%  a literal percent sign
+//
+// Width and precision count characters, so padding lines up for text that
+// isn't ASCII. Every verb but %% consumes one argument, and a format must
+// consume all of its arguments.
+func format(name string, args []object.Object) object.Object {
+	if len(args) == 0 {
+		return newError("wrong number of arguments. got=0, want at least 1")
+	}
+	if args[0].Type() != object.STRING_OBJ {
+		return newError("argument to `%s` must be STRING, got %s",
+			name, args[0].Type())
+	}
+
+	str, err := formatObjects(args[0].(*object.String).Value, args[1:])
+	if err != nil {
+		return err
+	}
+	return &object.String{Value: str}
+}
+
+func formatObjects(format string, args []object.Object) (string, *object.Error) {
+	var out strings.Builder
+	used := 0
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			out.WriteByte(format[i])
+			continue
+		}
+
+		// Collect the flags, width and precision verbatim; Go's fmt
+		// understands the same syntax.
+		start := i
+		i++
+		for i < len(format) && strings.IndexByte("-0+ ", format[i]) >= 0 {
+			i++
+		}
+		for i < len(format) && isDigit(format[i]) {
+			i++
+		}
+		if i < len(format) && format[i] == '.' {
+			i++
+			for i < len(format) && isDigit(format[i]) {
+				i++
+			}
+		}
+		if i >= len(format) {
+			return "", newError("incomplete verb at end of format %q", format)
+		}
+
+		verb, size := utf8.DecodeRuneInString(format[i:])
+		i += size - 1
+		spec := format[start:i] + string(verb)
+
+		if verb == '%' {
+			out.WriteByte('%')
+			continue
+		}
+		if used >= len(args) {
+			return "", newError("not enough arguments for format %q", format)
+		}
+		arg := args[used]
+		used++
+
+		value, err := formatValue(verb, arg)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&out, spec, value)
+	}
+
+	if used < len(args) {
+		return "", newError("too many arguments for format %q. got=%d, want=%d",
+			format, len(args), used)
+	}
+	return out.String(), nil
+}
+
+// formatValue converts arg to the Go value that fmt formats the way verb
+// describes.
+func formatValue(verb rune, arg object.Object) (interface{}, *object.Error) {
+	switch verb {
+	case 'v', 's':
+		return arg.Inspect(), nil
+	case 'q':
+		if str, ok := arg.(*object.String); ok {
+			return str.Value, nil
+		}
+	case 'd':
+		if i, ok := arg.(*object.Integer); ok {
+			return i.Value, nil
+		}
+	case 'x':
+		switch arg := arg.(type) {
+		case *object.Integer:
+			return arg.Value, nil
+		case *object.String:
+			return arg.Value, nil
+		}
+	case 'f', 'e', 'g':
+		if isNumber(arg) {
+			return toFloat(arg), nil
+		}
+	case 't':
+		if b, ok := arg.(*object.Boolean); ok {
+			return b.Value, nil
+		}
+	default:
+		return nil, newError("unknown format verb %%%c", verb)
+	}
+
+	return nil, newError("format verb %%%c can't format %s", verb, arg.Type())
+}
+
+func isDigit(ch byte) bool {
+	return '0' <= ch && ch <= '9'
+}
@@ -0,0 +1,211 @@
+package evaluator
+
+import (
+	"bytes"
+	"cathon/object"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// jsonBuiltins make up the json module. Objects become hashes with their
+// keys in document order, and hashes are written in insertion order.
+var jsonBuiltins = map[string]object.Object{
+	"parse": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if err := checkArgs("parse", args, object.STRING_OBJ); err != nil {
+				return err
+			}
+
+			dec := json.NewDecoder(strings.NewReader(stringArg(args, 0)))
+			dec.UseNumber()
+
+			value, err := parseJSON(dec)
+			if err != nil {
+				return newError("invalid JSON: %s", err)
+			}
+			if _, err := dec.Token(); err != io.EOF {
+				return newError("invalid JSON: unexpected data after value")
+			}
+			return value
+		},
+	},
+	// stringify(value) writes compact JSON. stringify(value, indent) puts
+	// each element on its own line, indented by indent, which is a number
+	// of spaces or the STRING to indent with.
+	"stringify": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			indent := ""
+			switch len(args) {
+			case 1:
+			case 2:
+				switch arg := args[1].(type) {
+				case *object.Integer:
+					if arg.Value < 0 || arg.Value > 10 {
+						return newError("indent for `stringify` must be between 0 and 10, got %d",
+							arg.Value)
+					}
+					indent = strings.Repeat(" ", int(arg.Value))
+				case *object.String:
+					indent = arg.Value
+				default:
+					return newError("argument to `stringify` must be INTEGER or STRING, got %s",
+						arg.Type())
+				}
+			default:
+				return newError("wrong number of arguments. got=%d, want=1 or 2",
+					len(args))
+			}
+
+			var out bytes.Buffer
+			if err := writeJSON(&out, args[0], make(map[object.Object]bool)); err != nil {
+				return err
+			}
+			if indent != "" {
+				var indented bytes.Buffer
+				json.Indent(&indented, out.Bytes(), "", indent)
+				out = indented
+			}
+			return &object.String{Value: out.String()}
+		},
+	},
+}
+
+// parseJSON reads one value from dec. Numbers without a fraction or
+// exponent become INTEGERs when they fit, and FLOATs otherwise.
+func parseJSON(dec *json.Decoder) (object.Object, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		if err == io.EOF {
+			err = errors.New("unexpected end of JSON input")
+		}
+		return nil, err
+	}
+
+	switch tok := tok.(type) {
+	case nil:
+		return NULL, nil
+	case bool:
+		return nativeBoolToBooleanObject(tok), nil
+	case string:
+		return &object.String{Value: tok}, nil
+	case json.Number:
+		if i, err := strconv.ParseInt(string(tok), 10, 64); err == nil {
+			return &object.Integer{Value: i}, nil
+		}
+		f, err := tok.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return &object.Float{Value: f}, nil
+	case json.Delim:
+		if tok == '[' {
+			elements := []object.Object{}
+			for dec.More() {
+				el, err := parseJSON(dec)
+				if err != nil {
+					return nil, err
+				}
+				elements = append(elements, el)
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+			return &object.Array{Elements: elements}, nil
+		}
+
+		hash := object.NewHash()
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := parseJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			hash.Set(&object.String{Value: key.(string)}, value)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return hash, nil
+	}
+
+	return nil, nil
+}
+
+// writeJSON writes obj to out as compact JSON. active holds the arrays and
+// hashes that are being written further up, to catch cycles.
+func writeJSON(out *bytes.Buffer, obj object.Object, active map[object.Object]bool) *object.Error {
+	switch obj := obj.(type) {
+	case *object.Null:
+		out.WriteString("null")
+	case *object.Boolean, *object.Integer:
+		out.WriteString(obj.Inspect())
+	case *object.Float:
+		if math.IsInf(obj.Value, 0) || math.IsNaN(obj.Value) {
+			return newError("cannot convert %s to JSON", obj.Inspect())
+		}
+		out.WriteString(obj.Inspect())
+	case *object.String:
+		writeJSONString(out, obj.Value)
+	case *object.Array:
+		if active[obj] {
+			return newError("cannot convert cyclic ARRAY to JSON")
+		}
+		active[obj] = true
+		defer delete(active, obj)
+
+		out.WriteByte('[')
+		for i, el := range obj.Elements {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			if err := writeJSON(out, el, active); err != nil {
+				return err
+			}
+		}
+		out.WriteByte(']')
+	case *object.Hash:
+		if active[obj] {
+			return newError("cannot convert cyclic HASH to JSON")
+		}
+		active[obj] = true
+		defer delete(active, obj)
+
+		out.WriteByte('{')
+		for i, pair := range obj.Pairs() {
+			key, ok := pair.Key.(*object.String)
+			if !ok {
+				return newError("JSON object keys must be STRING, got %s",
+					pair.Key.Type())
+			}
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			writeJSONString(out, key.Value)
+			out.WriteByte(':')
+			if err := writeJSON(out, pair.Value, active); err != nil {
+				return err
+			}
+		}
+		out.WriteByte('}')
+	default:
+		return newError("cannot convert %s to JSON", obj.Type())
+	}
+
+	return nil
+}
+
+// writeJSONString quotes str without escaping HTML characters, which
+// encoding/json does by default.
+func writeJSONString(out *bytes.Buffer, str string) {
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	enc.Encode(str)
+	out.Truncate(out.Len() - 1) // Encode adds a newline
+}
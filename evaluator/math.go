@@ -0,0 +1,261 @@
+package evaluator
+
+import (
+	"cathon/object"
+	"math"
+)
+
+// defaultSeed seeds every new Interpreter, so scripts that never call
+// math.seed still replay the same numbers.
+const defaultSeed = 0
+
+// mathBuiltins make up the pure part of the math module. The functions that
+// use the random source are in mathInterpreterBuiltins.
+var mathBuiltins = map[string]object.Object{
+	"pi": &object.Float{Value: math.Pi},
+	"abs": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if _, err := numberArgs("abs", args, 1); err != nil {
+				return err
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Integer:
+				if arg.Value == math.MinInt64 {
+					return newError("result of `abs` out of INTEGER range: 9223372036854775808")
+				}
+				if arg.Value < 0 {
+					return &object.Integer{Value: -arg.Value}
+				}
+				return arg
+			default:
+				return &object.Float{Value: math.Abs(toFloat(arg))}
+			}
+		},
+	},
+	"min": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			return extremum("min", args, func(a, b float64) bool { return a < b })
+		},
+	},
+	"max": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			return extremum("max", args, func(a, b float64) bool { return a > b })
+		},
+	},
+	"clamp": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			nums, err := numberArgs("clamp", args, 3)
+			if err != nil {
+				return err
+			}
+
+			x, lo, hi := nums[0], nums[1], nums[2]
+			switch {
+			case lo > hi:
+				return newError("bounds for `clamp` out of order: %s > %s",
+					args[1].Inspect(), args[2].Inspect())
+			case x < lo:
+				return args[1]
+			case x > hi:
+				return args[2]
+			default:
+				return args[0]
+			}
+		},
+	},
+	"floor": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			return rounding("floor", args, math.Floor)
+		},
+	},
+	"ceil": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			return rounding("ceil", args, math.Ceil)
+		},
+	},
+	"round": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			return rounding("round", args, math.Round)
+		},
+	},
+	"sqrt": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			nums, err := numberArgs("sqrt", args, 1)
+			if err != nil {
+				return err
+			}
+			if nums[0] < 0 {
+				return newError("argument to `sqrt` must not be negative, got %s",
+					args[0].Inspect())
+			}
+
+			return &object.Float{Value: math.Sqrt(nums[0])}
+		},
+	},
+	"sin": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			nums, err := numberArgs("sin", args, 1)
+			if err != nil {
+				return err
+			}
+
+			return &object.Float{Value: math.Sin(nums[0])}
+		},
+	},
+	"cos": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			nums, err := numberArgs("cos", args, 1)
+			if err != nil {
+				return err
+			}
+
+			return &object.Float{Value: math.Cos(nums[0])}
+		},
+	},
+	"atan2": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			nums, err := numberArgs("atan2", args, 2)
+			if err != nil {
+				return err
+			}
+
+			return &object.Float{Value: math.Atan2(nums[0], nums[1])}
+		},
+	},
+	"lerp": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			nums, err := numberArgs("lerp", args, 3)
+			if err != nil {
+				return err
+			}
+
+			a, b, t := nums[0], nums[1], nums[2]
+			return &object.Float{Value: a + (b-a)*t}
+		},
+	},
+}
+
+// mathInterpreterBuiltins use the random source of the Interpreter that
+// calls them.
+var mathInterpreterBuiltins = map[string]func(in *Interpreter, args ...object.Object) object.Object{
+	// random() returns a FLOAT in [0, 1), random(n) an INTEGER in [0, n)
+	// and random(lo, hi) an INTEGER in [lo, hi].
+	"random": func(in *Interpreter, args ...object.Object) object.Object {
+		switch len(args) {
+		case 0:
+			return &object.Float{Value: in.rand.Float64()}
+		case 1:
+			if err := checkArgs("random", args, object.INTEGER_OBJ); err != nil {
+				return err
+			}
+
+			n := args[0].(*object.Integer).Value
+			if n <= 0 {
+				return newError("argument to `random` must be positive, got %d", n)
+			}
+			return &object.Integer{Value: in.rand.Int64N(n)}
+		case 2:
+			if err := checkArgs("random", args, object.INTEGER_OBJ, object.INTEGER_OBJ); err != nil {
+				return err
+			}
+
+			lo := args[0].(*object.Integer).Value
+			hi := args[1].(*object.Integer).Value
+			if lo > hi {
+				return newError("bounds for `random` out of order: %d > %d", lo, hi)
+			}
+			// Work in uint64 so the full int64 range doesn't overflow.
+			span := uint64(hi) - uint64(lo)
+			if span == math.MaxUint64 {
+				return &object.Integer{Value: int64(in.rand.Uint64())}
+			}
+			return &object.Integer{Value: int64(uint64(lo) + in.rand.Uint64N(span+1))}
+		default:
+			return newError("wrong number of arguments. got=%d, want=0, 1 or 2",
+				len(args))
+		}
+	},
+	"seed": func(in *Interpreter, args ...object.Object) object.Object {
+		if err := checkArgs("seed", args, object.INTEGER_OBJ); err != nil {
+			return err
+		}
+
+		in.Seed(args[0].(*object.Integer).Value)
+		return NULL
+	},
+}
+
+// Seed restarts the random source of math.random, as math.seed does.
+func (in *Interpreter) Seed(seed int64) {
+	in.source.Seed(uint64(seed), 0)
+}
+
+// RandomState returns the state of the random source. Passing it to
+// SetRandomState, on this or another Interpreter, replays the same numbers
+// from that point on.
+func (in *Interpreter) RandomState() ([]byte, error) {
+	return in.source.MarshalBinary()
+}
+
+// SetRandomState restores a state returned by RandomState.
+func (in *Interpreter) SetRandomState(state []byte) error {
+	return in.source.UnmarshalBinary(state)
+}
+
+// numberArgs checks that args are n numbers and returns them as floats.
+func numberArgs(name string, args []object.Object, n int) ([]float64, *object.Error) {
+	if len(args) != n {
+		return nil, newError("wrong number of arguments. got=%d, want=%d",
+			len(args), n)
+	}
+
+	nums := make([]float64, n)
+	for i, arg := range args {
+		if !isNumber(arg) {
+			return nil, newError("argument to `%s` must be INTEGER or FLOAT, got %s",
+				name, arg.Type())
+		}
+		nums[i] = toFloat(arg)
+	}
+
+	return nums, nil
+}
+
+// extremum returns the argument that beats every other by better. Ties go
+// to the earliest argument.
+func extremum(name string, args []object.Object, better func(a, b float64) bool) object.Object {
+	if len(args) == 0 {
+		return newError("wrong number of arguments. got=0, want at least 1")
+	}
+	nums, err := numberArgs(name, args, len(args))
+	if err != nil {
+		return err
+	}
+
+	best := 0
+	for i := range nums {
+		if better(nums[i], nums[best]) {
+			best = i
+		}
+	}
+	return args[best]
+}
+
+// rounding applies round to a number and returns the result as an INTEGER.
+func rounding(name string, args []object.Object, round func(float64) float64) object.Object {
+	nums, err := numberArgs(name, args, 1)
+	if err != nil {
+		return err
+	}
+	if i, ok := args[0].(*object.Integer); ok {
+		return i
+	}
+
+	rounded, ok := floatToInteger(round(nums[0]))
+	if !ok {
+		return newError("result of `%s` out of INTEGER range: %s",
+			name, args[0].Inspect())
+	}
+	return &object.Integer{Value: rounded}
+}
@@ -0,0 +1,69 @@
+package evaluator
+
+import "cathon/object"
+
+// The functions below apply operators to values the way Eval does, so that
+// package vm gets the same results and errors without walking the AST.
+
+// Prefix applies the prefix operator, ! or -, to right.
+func Prefix(operator string, right object.Object) object.Object {
+	return evalPrefixExpression(operator, right)
+}
+
+// Infix applies the infix operator to left and right.
+func Infix(operator string, left, right object.Object) object.Object {
+	return evalInfixExpression(operator, left, right)
+}
+
+// Index evaluates left[index].
+func Index(left, index object.Object) object.Object {
+	return evalIndexExpression(left, index)
+}
+
+// Slice evaluates left[start:end:step]. Bounds left out are nil.
+func Slice(left, start, end, step object.Object) object.Object {
+	var bounds [3]*int64
+	for i, value := range []object.Object{start, end, step} {
+		if value == nil {
+			continue
+		}
+		integer, ok := value.(*object.Integer)
+		if !ok {
+			return newError("slice indices must be INTEGER, got %s", value.Type())
+		}
+		bounds[i] = &integer.Value
+	}
+	return sliceObject(left, bounds)
+}
+
+// Field evaluates left.name.
+func Field(left object.Object, name string) object.Object {
+	return evalDotExpression(left, name)
+}
+
+// CallMethod evaluates left.name(args...).
+func CallMethod(left object.Object, name string, args []object.Object) object.Object {
+	host, ok := left.(object.HostObject)
+	if !ok {
+		return newError("property access not supported: %s", left.Type())
+	}
+
+	result, err := host.CallMethod(name, args)
+	if err != nil {
+		return newError("%s", err)
+	}
+	return result
+}
+
+// SetField evaluates left.name = value.
+func SetField(left object.Object, name string, value object.Object) object.Object {
+	host, ok := left.(object.HostObject)
+	if !ok {
+		return newError("property assignment not supported: %s", left.Type())
+	}
+
+	if err := host.SetField(name, value); err != nil {
+		return newError("%s", err)
+	}
+	return value
+}
@@ -0,0 +1,22 @@
+package evaluator
+
+import "cathon/object"
+
+// ExitStatus returns the status a process running a script should exit
+// with, given its result: that of an INTEGER result, 1 for an error and 0
+// otherwise. err is the error to report, if any. Integers outside 0..255
+// are errors too, since exit statuses only have eight bits and would
+// wrap around.
+func ExitStatus(result object.Object) (status int, err *object.Error) {
+	switch result := result.(type) {
+	case *object.Error:
+		return 1, result
+	case *object.Integer:
+		if result.Value < 0 || result.Value > 255 {
+			return 1, newError("exit status out of range: %d", result.Value)
+		}
+		return int(result.Value), nil
+	default:
+		return 0, nil
+	}
+}
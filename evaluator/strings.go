@@ -0,0 +1,221 @@
+package evaluator
+
+import (
+	"cathon/object"
+	"strings"
+	"unicode/utf8"
+)
+
+// stringBuiltins make up the string module. Indexes and widths count code
+// points, like len and string indexing do.
+var stringBuiltins = map[string]object.Object{
+	"split": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			switch len(args) {
+			case 1:
+				if err := checkArgs("split", args, object.STRING_OBJ); err != nil {
+					return err
+				}
+				return stringsToArray(strings.Fields(stringArg(args, 0)))
+			case 2:
+				if err := checkArgs("split", args, object.STRING_OBJ, object.STRING_OBJ); err != nil {
+					return err
+				}
+				return stringsToArray(strings.Split(stringArg(args, 0), stringArg(args, 1)))
+			default:
+				return newError("wrong number of arguments. got=%d, want=1 or 2",
+					len(args))
+			}
+		},
+	},
+	"join": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if err := checkArgs("join", args, object.ARRAY_OBJ, object.STRING_OBJ); err != nil {
+				return err
+			}
+
+			arr := args[0].(*object.Array)
+			parts := make([]string, len(arr.Elements))
+			for i, el := range arr.Elements {
+				str, ok := el.(*object.String)
+				if !ok {
+					return newError("argument to `join` must be ARRAY of STRING, got %s element",
+						el.Type())
+				}
+				parts[i] = str.Value
+			}
+
+			return &object.String{Value: strings.Join(parts, stringArg(args, 1))}
+		},
+	},
+	"trim": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			switch len(args) {
+			case 1:
+				if err := checkArgs("trim", args, object.STRING_OBJ); err != nil {
+					return err
+				}
+				return &object.String{Value: strings.TrimSpace(stringArg(args, 0))}
+			case 2:
+				if err := checkArgs("trim", args, object.STRING_OBJ, object.STRING_OBJ); err != nil {
+					return err
+				}
+				return &object.String{Value: strings.Trim(stringArg(args, 0), stringArg(args, 1))}
+			default:
+				return newError("wrong number of arguments. got=%d, want=1 or 2",
+					len(args))
+			}
+		},
+	},
+	"upper": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if err := checkArgs("upper", args, object.STRING_OBJ); err != nil {
+				return err
+			}
+
+			return &object.String{Value: strings.ToUpper(stringArg(args, 0))}
+		},
+	},
+	"lower": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if err := checkArgs("lower", args, object.STRING_OBJ); err != nil {
+				return err
+			}
+
+			return &object.String{Value: strings.ToLower(stringArg(args, 0))}
+		},
+	},
+	"replace": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if err := checkArgs("replace", args, object.STRING_OBJ, object.STRING_OBJ, object.STRING_OBJ); err != nil {
+				return err
+			}
+
+			return &object.String{Value: strings.ReplaceAll(stringArg(args, 0), stringArg(args, 1), stringArg(args, 2))}
+		},
+	},
+	"contains": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if err := checkArgs("contains", args, object.STRING_OBJ, object.STRING_OBJ); err != nil {
+				return err
+			}
+
+			return nativeBoolToBooleanObject(strings.Contains(stringArg(args, 0), stringArg(args, 1)))
+		},
+	},
+	"startsWith": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if err := checkArgs("startsWith", args, object.STRING_OBJ, object.STRING_OBJ); err != nil {
+				return err
+			}
+
+			return nativeBoolToBooleanObject(strings.HasPrefix(stringArg(args, 0), stringArg(args, 1)))
+		},
+	},
+	"endsWith": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if err := checkArgs("endsWith", args, object.STRING_OBJ, object.STRING_OBJ); err != nil {
+				return err
+			}
+
+			return nativeBoolToBooleanObject(strings.HasSuffix(stringArg(args, 0), stringArg(args, 1)))
+		},
+	},
+	"indexOf": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if err := checkArgs("indexOf", args, object.STRING_OBJ, object.STRING_OBJ); err != nil {
+				return err
+			}
+
+			str := stringArg(args, 0)
+			i := strings.Index(str, stringArg(args, 1))
+			if i < 0 {
+				return &object.Integer{Value: -1}
+			}
+			return &object.Integer{Value: int64(utf8.RuneCountInString(str[:i]))}
+		},
+	},
+	"repeat": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if err := checkArgs("repeat", args, object.STRING_OBJ, object.INTEGER_OBJ); err != nil {
+				return err
+			}
+
+			return evalStringRepetition(args[0], args[1])
+		},
+	},
+	"padLeft": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			str, padding, err := padArgs("padLeft", args)
+			if err != nil {
+				return err
+			}
+
+			return &object.String{Value: padding + str}
+		},
+	},
+	"padRight": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			str, padding, err := padArgs("padRight", args)
+			if err != nil {
+				return err
+			}
+
+			return &object.String{Value: str + padding}
+		},
+	},
+}
+
+// padArgs validates padLeft(str, width) and padLeft(str, width, pad) and
+// returns str along with the padding that makes it width code points long.
+func padArgs(name string, args []object.Object) (string, string, *object.Error) {
+	pad := " "
+	switch len(args) {
+	case 2:
+		if err := checkArgs(name, args, object.STRING_OBJ, object.INTEGER_OBJ); err != nil {
+			return "", "", err
+		}
+	case 3:
+		if err := checkArgs(name, args, object.STRING_OBJ, object.INTEGER_OBJ, object.STRING_OBJ); err != nil {
+			return "", "", err
+		}
+		pad = stringArg(args, 2)
+		if pad == "" {
+			return "", "", newError("padding for `%s` must not be empty", name)
+		}
+	default:
+		return "", "", newError("wrong number of arguments. got=%d, want=2 or 3",
+			len(args))
+	}
+
+	str := stringArg(args, 0)
+	width := args[1].(*object.Integer).Value
+	if width > maxStringLength {
+		return "", "", newError("width for `%s` too large: %d", name, width)
+	}
+
+	missing := int(width) - utf8.RuneCountInString(str)
+	if missing <= 0 {
+		return str, "", nil
+	}
+
+	padRunes := []rune(pad)
+	padding := make([]rune, missing)
+	for i := range padding {
+		padding[i] = padRunes[i%len(padRunes)]
+	}
+
+	return str, string(padding), nil
+}
+
+func stringArg(args []object.Object, i int) string {
+	return args[i].(*object.String).Value
+}
+
+func stringsToArray(strs []string) *object.Array {
+	elements := make([]object.Object, len(strs))
+	for i, s := range strs {
+		elements[i] = &object.String{Value: s}
+	}
+	return &object.Array{Elements: elements}
+}
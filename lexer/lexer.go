@@ -6,23 +6,42 @@ import (
 )
 
 type Lexer struct {
-	input        string
+	input        []rune
 	position     int
 	readPosition int
 	ch           rune
+	line         int // of ch
+	column       int // of ch
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: []rune(input), line: 1}
 	l.ReadChar()
+	l.SkipShebang()
 	return l
 }
 
+// SkipShebang skips a "#!" line at the start of the input, so scripts can
+// be run directly on Unix. The newline is kept.
+func (l *Lexer) SkipShebang() {
+	if l.position != 0 || l.ch != '#' || l.PeekChar() != '!' {
+		return
+	}
+	for l.ch != '\n' && l.ch != 0 {
+		l.ReadChar()
+	}
+}
+
 func (l *Lexer) ReadChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+	l.column++
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
-		l.ch = []rune(l.input)[l.readPosition]
+		l.ch = l.input[l.readPosition]
 	}
 	l.position = l.readPosition
 	l.readPosition += 1
@@ -32,7 +51,7 @@ func (l *Lexer) PeekChar() rune {
 	if l.readPosition >= len(l.input) {
 		return 0
 	} else {
-		return []rune(l.input)[l.readPosition]
+		return l.input[l.readPosition]
 	}
 }
 
@@ -40,10 +59,11 @@ func NewToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
 
-func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
+func (l *Lexer) NextToken() (tok token.Token) {
 
 	l.SkipWhiteSpace()
+	line, column := l.line, l.column
+	defer func() { tok.Line, tok.Column = line, column }()
 
 	switch l.ch {
 	case '=':
@@ -73,15 +93,31 @@ func (l *Lexer) NextToken() token.Token {
 	case '*':
 		tok = NewToken(token.ASTERISK, l.ch)
 	case '<':
-		tok = NewToken(token.LT, l.ch)
+		if l.PeekChar() == '=' {
+			ch := l.ch
+			l.ReadChar()
+			literal := string(ch) + string(l.ch)
+			tok = token.Token{Type: token.LTE, Literal: literal}
+		} else {
+			tok = NewToken(token.LT, l.ch)
+		}
 	case '>':
-		tok = NewToken(token.GT, l.ch)
+		if l.PeekChar() == '=' {
+			ch := l.ch
+			l.ReadChar()
+			literal := string(ch) + string(l.ch)
+			tok = token.Token{Type: token.GTE, Literal: literal}
+		} else {
+			tok = NewToken(token.GT, l.ch)
+		}
 	case ';':
 		tok = NewToken(token.SEMICOLON, l.ch)
 	case ':':
 		tok = NewToken(token.COLON, l.ch)
 	case ',':
 		tok = NewToken(token.COMMA, l.ch)
+	case '.':
+		tok = NewToken(token.DOT, l.ch)
 	case '{':
 		tok = NewToken(token.LBRACE, l.ch)
 	case '}':
@@ -101,13 +137,18 @@ func (l *Lexer) NextToken() token.Token {
 		tok.Literal = ""
 		tok.Type = token.EOF
 	default:
-		if unicode.IsLetter(l.ch) {
+		if isIdentifierStart(l.ch) {
 			tok.Literal = l.ReadIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
 			return tok
 		} else if unicode.IsDigit(l.ch) {
-			tok.Type = token.INT
 			tok.Literal = l.ReadNumber()
+			tok.Type = token.INT
+			if l.ch == '.' && unicode.IsDigit(l.PeekChar()) {
+				l.ReadChar()
+				tok.Literal += "." + l.ReadNumber()
+				tok.Type = token.FLOAT
+			}
 			return tok
 		} else {
 			tok = NewToken(token.ILLEGAL, l.ch)
@@ -118,12 +159,18 @@ func (l *Lexer) NextToken() token.Token {
 	return tok
 }
 
+func isIdentifierStart(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_'
+}
+
+// Identifiers start with a letter or underscore and may contain digits
+// after that, as in atan2.
 func (l *Lexer) ReadIdentifier() string {
 	position := l.position
-	for unicode.IsLetter(l.ch) {
+	for isIdentifierStart(l.ch) || unicode.IsDigit(l.ch) {
 		l.ReadChar()
 	}
-	return l.input[position:l.position]
+	return string(l.input[position:l.position])
 }
 
 func (l *Lexer) ReadNumber() string {
@@ -131,7 +178,7 @@ func (l *Lexer) ReadNumber() string {
 	for unicode.IsDigit(l.ch) {
 		l.ReadChar()
 	}
-	return l.input[position:l.position]
+	return string(l.input[position:l.position])
 }
 
 func (l *Lexer) SkipWhiteSpace() {
@@ -148,5 +195,5 @@ func (l *Lexer) ReadString() string {
 			break
 		}
 	}
-	return l.input[position:l.position]
+	return string(l.input[position:l.position])
 }
\ No newline at end of file
@@ -2,6 +2,7 @@ package lexer
 
 import (
 	"cathon/token"
+	"strings"
 	"testing"
 )
 
@@ -29,6 +30,11 @@ if (5 < 10) {
 "foo bar"
 [1, 2];
 {"foo": "bar"}
+cat.x = 1;
+1 <= 2 >= 3;
+"a" in "cat";
+let 고양이 = "야옹";
+math.atan2(1.5, 0.25) + _x1;
 `
 
 	tests := []struct {
@@ -121,6 +127,38 @@ if (5 < 10) {
 		{token.COLON, ":"},
 		{token.STRING, "bar"},
 		{token.RBRACE, "}"},
+		{token.IDENT, "cat"},
+		{token.DOT, "."},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.INT, "1"},
+		{token.LTE, "<="},
+		{token.INT, "2"},
+		{token.GTE, ">="},
+		{token.INT, "3"},
+		{token.SEMICOLON, ";"},
+		{token.STRING, "a"},
+		{token.IN, "in"},
+		{token.STRING, "cat"},
+		{token.SEMICOLON, ";"},
+		{token.LET, "let"},
+		{token.IDENT, "고양이"},
+		{token.ASSIGN, "="},
+		{token.STRING, "야옹"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "math"},
+		{token.DOT, "."},
+		{token.IDENT, "atan2"},
+		{token.LPAREN, "("},
+		{token.FLOAT, "1.5"},
+		{token.COMMA, ","},
+		{token.FLOAT, "0.25"},
+		{token.RPAREN, ")"},
+		{token.PLUS, "+"},
+		{token.IDENT, "_x1"},
+		{token.SEMICOLON, ";"},
 		{token.EOF, ""},
 	}
 
@@ -140,3 +178,56 @@ if (5 < 10) {
 		}
 	}
 }
+
+func TestShebang(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"#!/usr/bin/env cathon\nputs(1)", []string{"puts", "(", "1", ")"}},
+		{"#!/usr/bin/env cathon", []string{}},
+		{"x #! y", []string{"x", "#", "!", "y"}},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		literals := []string{}
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+			literals = append(literals, tok.Literal)
+		}
+
+		if strings.Join(literals, " ") != strings.Join(tt.expected, " ") {
+			t.Errorf("wrong tokens for %q. expected=%q, got=%q",
+				tt.input, tt.expected, literals)
+		}
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := "#!/bin/cathon\nlet 고양이 = \"야옹\";\n  x >= 10\n"
+
+	tests := []struct {
+		literal string
+		line    int
+		column  int
+	}{
+		{"let", 2, 1},
+		{"고양이", 2, 5},
+		{"=", 2, 9},
+		{"야옹", 2, 11},
+		{";", 2, 15},
+		{"x", 3, 3},
+		{">=", 3, 5},
+		{"10", 3, 8},
+		{"", 4, 1},
+	}
+
+	l := New(input)
+	for _, tt := range tests {
+		tok := l.NextToken()
+		if tok.Literal != tt.literal || tok.Line != tt.line || tok.Column != tt.column {
+			t.Errorf("wrong token. expected=%q at %d:%d, got=%q at %d:%d",
+				tt.literal, tt.line, tt.column, tok.Literal, tok.Line, tok.Column)
+		}
+	}
+}
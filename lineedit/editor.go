@@ -0,0 +1,371 @@
+// Package lineedit reads lines from a terminal with cursor movement,
+// history and tab completion. It needs the terminal in raw mode; see
+// MakeRaw.
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// ErrInterrupted is returned by ReadLine when the user presses Ctrl-C.
+var ErrInterrupted = errors.New("interrupted")
+
+// maxHistory is how many lines of history are kept.
+const maxHistory = 1000
+
+// Editor edits one line at a time. The keys it knows are
+//
+//	Left, Ctrl-B / Right, Ctrl-F   move by a character
+//	Home, Ctrl-A / End, Ctrl-E     move to the start or end
+//	Up, Ctrl-P / Down, Ctrl-N      move through history
+//	Backspace / Delete, Ctrl-D     delete before or under the cursor
+//	Ctrl-K / Ctrl-U                delete to the end or start
+//	Ctrl-W                         delete the word before the cursor
+//	Ctrl-L                         clear the screen
+//	Tab                            complete the word before the cursor
+//	Ctrl-C                         give up on the line
+//	Ctrl-D on an empty line        end of input
+type Editor struct {
+	// Complete returns the completions of word, which is the run of
+	// letters, digits, underscores and dots before the cursor.
+	Complete func(word string) []string
+
+	in  *bufio.Reader
+	out io.Writer
+
+	history     []string
+	historyFile string
+
+	prompt  string
+	line    []rune
+	pos     int
+	index   int    // position in history; len(history) is the new line
+	pending []rune // the new line while browsing history
+}
+
+func New(in io.Reader, out io.Writer) *Editor {
+	return &Editor{in: bufio.NewReader(in), out: out}
+}
+
+// ReadLine shows prompt and returns the line the user enters, without the
+// newline. It returns io.EOF at the end of input and ErrInterrupted when
+// the user gives up on the line.
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	e.prompt = prompt
+	e.line = e.line[:0]
+	e.pos = 0
+	e.index = len(e.history)
+	e.pending = nil
+	e.refresh()
+
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			if err == io.EOF && len(e.line) > 0 {
+				io.WriteString(e.out, "\r\n")
+				return string(e.line), nil
+			}
+			return "", err
+		}
+
+		switch r {
+		case '\r', '\n':
+			io.WriteString(e.out, "\r\n")
+			return string(e.line), nil
+		case ctrl('C'):
+			io.WriteString(e.out, "^C\r\n")
+			return "", ErrInterrupted
+		case ctrl('D'):
+			if len(e.line) == 0 {
+				io.WriteString(e.out, "\r\n")
+				return "", io.EOF
+			}
+			e.delete(e.pos, e.pos+1)
+		case ctrl('A'):
+			e.pos = 0
+		case ctrl('E'):
+			e.pos = len(e.line)
+		case ctrl('B'):
+			e.move(-1)
+		case ctrl('F'):
+			e.move(1)
+		case ctrl('P'):
+			e.browse(-1)
+		case ctrl('N'):
+			e.browse(1)
+		case ctrl('H'), 127:
+			if e.pos > 0 {
+				e.delete(e.pos-1, e.pos)
+			}
+		case ctrl('K'):
+			e.delete(e.pos, len(e.line))
+		case ctrl('U'):
+			e.delete(0, e.pos)
+		case ctrl('W'):
+			start := e.pos
+			for start > 0 && unicode.IsSpace(e.line[start-1]) {
+				start--
+			}
+			for start > 0 && !unicode.IsSpace(e.line[start-1]) {
+				start--
+			}
+			e.delete(start, e.pos)
+		case ctrl('L'):
+			io.WriteString(e.out, "\x1b[H\x1b[2J")
+		case '\t':
+			e.complete()
+		case 0x1b:
+			if err := e.escape(); err != nil {
+				return "", err
+			}
+		default:
+			if unicode.IsPrint(r) {
+				e.insert([]rune{r})
+			}
+		}
+
+		e.refresh()
+	}
+}
+
+// escape handles the rest of an escape sequence, such as an arrow key.
+func (e *Editor) escape() error {
+	r, _, err := e.in.ReadRune()
+	if err != nil {
+		return err
+	}
+	if r != '[' && r != 'O' {
+		return nil
+	}
+
+	// Parameters are digits and semicolons; a byte from '@' to '~' ends
+	// the sequence.
+	var params strings.Builder
+	for {
+		r, _, err = e.in.ReadRune()
+		if err != nil {
+			return err
+		}
+		if r >= '@' && r <= '~' {
+			break
+		}
+		params.WriteRune(r)
+	}
+
+	switch r {
+	case 'A':
+		e.browse(-1)
+	case 'B':
+		e.browse(1)
+	case 'C':
+		e.move(1)
+	case 'D':
+		e.move(-1)
+	case 'H':
+		e.pos = 0
+	case 'F':
+		e.pos = len(e.line)
+	case '~':
+		switch params.String() {
+		case "1", "7":
+			e.pos = 0
+		case "4", "8":
+			e.pos = len(e.line)
+		case "3":
+			e.delete(e.pos, e.pos+1)
+		}
+	}
+	return nil
+}
+
+func (e *Editor) insert(runes []rune) {
+	line := make([]rune, 0, len(e.line)+len(runes))
+	line = append(line, e.line[:e.pos]...)
+	line = append(line, runes...)
+	e.line = append(line, e.line[e.pos:]...)
+	e.pos += len(runes)
+}
+
+// delete removes line[from:to], clipped to the line.
+func (e *Editor) delete(from, to int) {
+	if to > len(e.line) {
+		to = len(e.line)
+	}
+	if from >= to {
+		return
+	}
+
+	e.line = append(e.line[:from], e.line[to:]...)
+	if e.pos > to {
+		e.pos -= to - from
+	} else if e.pos > from {
+		e.pos = from
+	}
+}
+
+func (e *Editor) move(delta int) {
+	e.pos += delta
+	if e.pos < 0 {
+		e.pos = 0
+	}
+	if e.pos > len(e.line) {
+		e.pos = len(e.line)
+	}
+}
+
+// browse moves delta entries through history, keeping the new line so
+// that coming back down restores it.
+func (e *Editor) browse(delta int) {
+	index := e.index + delta
+	if index < 0 || index > len(e.history) {
+		return
+	}
+
+	if e.index == len(e.history) {
+		e.pending = append([]rune(nil), e.line...)
+	}
+	e.index = index
+	if index == len(e.history) {
+		e.line = append([]rune(nil), e.pending...)
+	} else {
+		e.line = []rune(e.history[index])
+	}
+	e.pos = len(e.line)
+}
+
+// complete inserts what all completions of the word before the cursor
+// have in common, and lists them when that adds nothing.
+func (e *Editor) complete() {
+	if e.Complete == nil {
+		return
+	}
+
+	start := e.pos
+	for start > 0 && isWordRune(e.line[start-1]) {
+		start--
+	}
+	word := string(e.line[start:e.pos])
+
+	candidates := []string{}
+	for _, c := range e.Complete(word) {
+		if strings.HasPrefix(c, word) {
+			candidates = append(candidates, c)
+		}
+	}
+	if len(candidates) == 0 {
+		io.WriteString(e.out, "\a")
+		return
+	}
+
+	common := commonPrefix(candidates)
+	if len(common) > len(word) {
+		e.insert([]rune(common[len(word):]))
+		return
+	}
+	if len(candidates) > 1 {
+		io.WriteString(e.out, "\r\n"+strings.Join(candidates, "  ")+"\r\n")
+	}
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.'
+}
+
+// commonPrefix returns the longest prefix of whole characters that all
+// words share, so completing never splits a multibyte character.
+func commonPrefix(words []string) string {
+	prefix := []rune(words[0])
+	for _, word := range words[1:] {
+		n := 0
+		for _, r := range word {
+			if n == len(prefix) || prefix[n] != r {
+				break
+			}
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	return string(prefix)
+}
+
+// refresh redraws the prompt and line and puts the cursor in place.
+func (e *Editor) refresh() {
+	var b strings.Builder
+	b.WriteString("\r")
+	b.WriteString(e.prompt)
+	b.WriteString(string(e.line))
+	b.WriteString("\x1b[K")
+	if back := width(e.line[e.pos:]); back > 0 {
+		fmt.Fprintf(&b, "\x1b[%dD", back)
+	}
+	io.WriteString(e.out, b.String())
+}
+
+func ctrl(key rune) rune {
+	return key & 0x1f
+}
+
+// LoadHistory reads the history saved in path, and makes AddHistory save
+// new lines to it. A missing file is an empty history.
+func (e *Editor) LoadHistory(path string) error {
+	e.historyFile = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return nil
+	}
+	if len(lines) > maxHistory {
+		lines = lines[len(lines)-maxHistory:]
+		// Rewrite the file so it doesn't grow without bound.
+		if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+			return err
+		}
+	}
+	e.history = lines
+	return nil
+}
+
+// AddHistory remembers line, unless it is empty or repeats the last line.
+func (e *Editor) AddHistory(line string) error {
+	if strings.TrimSpace(line) == "" ||
+		(len(e.history) > 0 && e.history[len(e.history)-1] == line) {
+		return nil
+	}
+
+	e.history = append(e.history, line)
+	if len(e.history) > maxHistory {
+		e.history = e.history[len(e.history)-maxHistory:]
+	}
+
+	if e.historyFile == "" {
+		return nil
+	}
+	f, err := os.OpenFile(e.historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, line+"\n"); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// History returns the remembered lines, oldest first.
+func (e *Editor) History() []string {
+	return append([]string(nil), e.history...)
+}
@@ -0,0 +1,193 @@
+package lineedit
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	left  = "\x1b[D"
+	right = "\x1b[C"
+	up    = "\x1b[A"
+	down  = "\x1b[B"
+	home  = "\x1b[H"
+	end   = "\x1b[F"
+	del   = "\x1b[3~"
+	bs    = "\x7f"
+)
+
+func TestReadLine(t *testing.T) {
+	tests := []struct {
+		keys     string
+		expected string
+	}{
+		{"let x = 1\r", "let x = 1"},
+		{"ac" + left + "b\r", "abc"},
+		{"bc" + home + "a" + end + "d\r", "abcd"},
+		{"abc" + bs + bs + "x\r", "ax"},
+		{"abc" + left + left + del + "\r", "ac"},
+		{"abc" + left + "\x04\r", "ab"},
+		{"hello world" + left + left + "\x0b\r", "hello wor"},
+		{"hello world" + left + left + "\x15\r", "ld"},
+		{"let answer = 42" + "\x17\x17\r", "let answer "},
+		{"b\x01a\x05c\r", "abc"},
+		{"ac\x02b\x06d\r", "abcd"},
+		{"고양" + left + "x" + right + "이\r", "고x양이"},
+		{left + bs + del + right + "x\r", "x"},
+		{"\x1b[1;5Cok\r", "ok"},
+		{"partial", "partial"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		e := New(strings.NewReader(tt.keys), &out)
+
+		line, err := e.ReadLine(">> ")
+		if err != nil {
+			t.Errorf("ReadLine(%q) returned error: %s", tt.keys, err)
+			continue
+		}
+		if line != tt.expected {
+			t.Errorf("ReadLine(%q) wrong. expected=%q, got=%q", tt.keys, tt.expected, line)
+		}
+	}
+}
+
+func TestReadLineEndings(t *testing.T) {
+	var out bytes.Buffer
+	e := New(strings.NewReader("abc\x03\x04"), &out)
+
+	if _, err := e.ReadLine(">> "); err != ErrInterrupted {
+		t.Errorf("Ctrl-C wrong. expected=%v, got=%v", ErrInterrupted, err)
+	}
+	if _, err := e.ReadLine(">> "); err != io.EOF {
+		t.Errorf("Ctrl-D on empty line wrong. expected=%v, got=%v", io.EOF, err)
+	}
+}
+
+func TestRefreshPlacesCursor(t *testing.T) {
+	var out bytes.Buffer
+	e := New(strings.NewReader("고양이"+left+left+"\r"), &out)
+	e.ReadLine("> ")
+
+	// The last redraw leaves the cursor before 양, which is four columns
+	// from the end because Hangul is double width.
+	redraws := strings.Split(out.String(), "\r")
+	last := redraws[len(redraws)-2]
+	if last != "> 고양이\x1b[K\x1b[4D" {
+		t.Errorf("wrong redraw. got=%q", last)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+
+	e := New(strings.NewReader(""), io.Discard)
+	if err := e.LoadHistory(path); err != nil {
+		t.Fatalf("LoadHistory on a missing file failed: %s", err)
+	}
+	for _, line := range []string{"first", "second", "second", "", "third"} {
+		if err := e.AddHistory(line); err != nil {
+			t.Fatalf("AddHistory failed: %s", err)
+		}
+	}
+
+	keys := up + up + "\r" + // second
+		up + up + up + up + down + "\r" + // second, after stopping at first
+		"new" + up + down + "\r" // back to the unfinished line
+	e = New(strings.NewReader(keys), io.Discard)
+	if err := e.LoadHistory(path); err != nil {
+		t.Fatalf("LoadHistory failed: %s", err)
+	}
+
+	expectedHistory := []string{"first", "second", "third"}
+	if got := e.History(); strings.Join(got, ",") != strings.Join(expectedHistory, ",") {
+		t.Fatalf("wrong history loaded. expected=%v, got=%v", expectedHistory, got)
+	}
+
+	for _, expected := range []string{"second", "second", "new"} {
+		line, err := e.ReadLine(">> ")
+		if err != nil {
+			t.Fatalf("ReadLine failed: %s", err)
+		}
+		if line != expected {
+			t.Errorf("wrong line. expected=%q, got=%q", expected, line)
+		}
+	}
+}
+
+func TestHistoryLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+
+	e := New(strings.NewReader(""), io.Discard)
+	e.LoadHistory(path)
+	for i := 0; i < maxHistory+5; i++ {
+		e.AddHistory(strings.Repeat("x", i+1))
+	}
+
+	e = New(strings.NewReader(""), io.Discard)
+	e.LoadHistory(path)
+	history := e.History()
+	if len(history) != maxHistory {
+		t.Fatalf("wrong history length. expected=%d, got=%d", maxHistory, len(history))
+	}
+	if len(history[0]) != 6 {
+		t.Errorf("oldest lines not dropped first. got=%q", history[0])
+	}
+}
+
+func TestComplete(t *testing.T) {
+	words := []string{"len", "let", "math.sqrt", "math.sin", "string", "가x", "각y"}
+	tests := []struct {
+		keys     string
+		expected string
+		listed   bool
+	}{
+		{"str\t\r", "string", false},
+		{"le\t\r", "le", true},
+		{"le\tn\r", "len", true},
+		{"x = math.sq\t(2)\r", "x = math.sqrt(2)", false},
+		{"math.\t\r", "math.s", false},
+		{"nothing\t\r", "nothing", false},
+		{"(l\t)\r", "(le)", false},
+		{"가\t\r", "가x", false},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		e := New(strings.NewReader(tt.keys), &out)
+		e.Complete = func(word string) []string { return words }
+
+		line, err := e.ReadLine(">> ")
+		if err != nil {
+			t.Fatalf("ReadLine failed: %s", err)
+		}
+		if line != tt.expected {
+			t.Errorf("ReadLine(%q) wrong. expected=%q, got=%q", tt.keys, tt.expected, line)
+		}
+		if listed := strings.Contains(out.String(), "len  let"); listed != tt.listed {
+			t.Errorf("ReadLine(%q) listed candidates: %t, want %t", tt.keys, listed, tt.listed)
+		}
+	}
+}
+
+func TestCommonPrefix(t *testing.T) {
+	tests := []struct {
+		words    []string
+		expected string
+	}{
+		{[]string{"len", "let"}, "le"},
+		{[]string{"string"}, "string"},
+		{[]string{"가x", "각y"}, ""},
+		{[]string{"가나x", "가낙y"}, "가"},
+	}
+
+	for _, tt := range tests {
+		if got := commonPrefix(tt.words); got != tt.expected {
+			t.Errorf("commonPrefix(%q) wrong. expected=%q, got=%q", tt.words, tt.expected, got)
+		}
+	}
+}
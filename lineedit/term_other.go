@@ -0,0 +1,17 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package lineedit
+
+import (
+	"errors"
+	"os"
+)
+
+// IsTerminal reports whether f is a terminal. Raw mode isn't supported
+// on this system, so it never is.
+func IsTerminal(f *os.File) bool { return false }
+
+// MakeRaw isn't supported on this system.
+func MakeRaw(f *os.File) (restore func() error, err error) {
+	return nil, errors.New("lineedit: raw mode not supported")
+}
@@ -0,0 +1,175 @@
+package lsp
+
+import (
+	"cathon/ast"
+	"cathon/checker"
+	"cathon/lexer"
+	"cathon/parser"
+	"cathon/token"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// document is an open file and what the server knows about it.
+type document struct {
+	uri     string
+	version int
+	lines   []string
+
+	program     *ast.Program
+	parseErrors []string
+	errorTokens []token.Token
+	info        *checker.Info
+}
+
+func newDocument(uri string, version int, text string, globals []string) *document {
+	p := parser.New(lexer.New(text))
+	program := p.ParseProgram()
+
+	return &document{
+		uri:         uri,
+		version:     version,
+		lines:       strings.Split(text, "\n"),
+		program:     program,
+		parseErrors: p.Errors(),
+		errorTokens: p.ErrorTokens(),
+		info:        checker.Analyze(program, globals...),
+	}
+}
+
+func (d *document) diagnostics() []Diagnostic {
+	diagnostics := []Diagnostic{}
+	for i, msg := range d.parseErrors {
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    d.tokenRange(d.errorTokens[i]),
+			Severity: SeverityError,
+			Source:   "cathon",
+			Message:  msg,
+		})
+	}
+
+	for _, diag := range d.info.Diagnostics {
+		severity := SeverityWarning
+		if diag.Severity == checker.Error {
+			severity = SeverityError
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    d.wordRange(diag.Line, diag.Column),
+			Severity: severity,
+			Code:     diag.Code,
+			Source:   "cathon",
+			Message:  diag.Message,
+		})
+	}
+	return diagnostics
+}
+
+// identifierAt returns the identifier that line and column, as the lexer
+// counts them, fall in or just after.
+func (d *document) identifierAt(line, column int) *ast.Identifier {
+	at := func(ident *ast.Identifier) bool {
+		start := ident.Token.Column
+		return ident.Token.Line == line &&
+			start <= column && column <= start+utf8.RuneCountInString(ident.Value)
+	}
+
+	for ident := range d.info.Definitions {
+		if at(ident) {
+			return ident
+		}
+	}
+	for ident := range d.info.Builtins {
+		if at(ident) {
+			return ident
+		}
+	}
+	return nil
+}
+
+// position converts a line and column as the lexer counts them.
+func (d *document) position(line, column int) Position {
+	if line < 1 || line > len(d.lines) {
+		return Position{Line: line - 1}
+	}
+	runes := []rune(d.lines[line-1])
+	if column-1 < len(runes) {
+		runes = runes[:max(column-1, 0)]
+	}
+	return Position{Line: line - 1, Character: len(utf16.Encode(runes))}
+}
+
+// lineColumn converts pos to a line and column as the lexer counts them.
+func (d *document) lineColumn(pos Position) (line, column int) {
+	if pos.Line < 0 || pos.Line >= len(d.lines) {
+		return pos.Line + 1, pos.Character + 1
+	}
+
+	units := 0
+	column = 1
+	for _, r := range d.lines[pos.Line] {
+		if units >= pos.Character {
+			break
+		}
+		units += utf16.RuneLen(r)
+		column++
+	}
+	return pos.Line + 1, column
+}
+
+func (d *document) tokenRange(tok token.Token) Range {
+	length := utf8.RuneCountInString(tok.Literal)
+	if tok.Type == token.STRING {
+		length += 2 // the quotes
+	}
+	return Range{
+		Start: d.position(tok.Line, tok.Column),
+		End:   d.position(tok.Line, tok.Column+length),
+	}
+}
+
+// wordRange covers the identifier starting at line and column, or the one
+// character there if it doesn't start an identifier.
+func (d *document) wordRange(line, column int) Range {
+	end := column + 1
+	if line >= 1 && line <= len(d.lines) {
+		runes := []rune(d.lines[line-1])
+		i := column - 1
+		for i < len(runes) && isWordRune(runes[i]) {
+			i++
+		}
+		if i > column-1 {
+			end = i + 1
+		}
+	}
+	return Range{Start: d.position(line, column), End: d.position(line, end)}
+}
+
+// qualifierAt returns the identifier before the dot in front of the word
+// that ends at line and column, such as math while typing math.sq.
+func (d *document) qualifierAt(line, column int) (string, bool) {
+	if line < 1 || line > len(d.lines) {
+		return "", false
+	}
+	runes := []rune(d.lines[line-1])
+
+	start := min(column-1, len(runes))
+	for start > 0 && isWordRune(runes[start-1]) {
+		start--
+	}
+	if start == 0 || runes[start-1] != '.' {
+		return "", false
+	}
+
+	end := start - 1
+	start = end
+	for start > 0 && isWordRune(runes[start-1]) {
+		start--
+	}
+	return string(runes[start:end]), true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
@@ -0,0 +1,368 @@
+// Package lsp implements a Language Server Protocol server for cathon
+// scripts, so editors such as VS Code can show the checker's diagnostics as
+// you type and offer completion, hover, go to definition and an outline.
+package lsp
+
+import (
+	"cathon/ast"
+	"cathon/evaluator"
+	"cathon/object"
+	"cathon/token"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+)
+
+// Server serves one client over a pair of streams, usually stdin and
+// stdout. Documents are synced whole on every change.
+type Server struct {
+	// Globals are the names the host defines besides the builtins, as for
+	// checker.Check.
+	Globals []string
+
+	conn        *conn
+	docs        map[string]*document
+	builtins    map[string]object.Object
+	initialized bool
+	shutdown    bool
+}
+
+// ErrExitWithoutShutdown is returned by Serve when the client asks the
+// server to exit without shutting it down first.
+var ErrExitWithoutShutdown = errors.New("lsp: exit without shutdown")
+
+// Serve reads requests from in and writes responses and notifications to
+// out until the client sends exit or closes in.
+func (srv *Server) Serve(in io.Reader, out io.Writer) error {
+	srv.conn = newConn(in, out)
+	srv.docs = make(map[string]*document)
+	srv.builtins = evaluator.New().Builtins()
+
+	for {
+		msg, err := srv.conn.read()
+		if err == io.EOF {
+			return nil
+		}
+		if rerr, ok := err.(*responseError); ok {
+			srv.conn.reply(json.RawMessage("null"), nil, rerr)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if msg.Method == "exit" {
+			if !srv.shutdown {
+				return ErrExitWithoutShutdown
+			}
+			return nil
+		}
+		if err := srv.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+type requestHandler func(srv *Server, params json.RawMessage) (interface{}, error)
+type notificationHandler func(srv *Server, params json.RawMessage) error
+
+var requests = map[string]requestHandler{
+	"initialize":                  (*Server).initialize,
+	"shutdown":                    (*Server).shutdownRequest,
+	"textDocument/completion":     (*Server).completion,
+	"textDocument/hover":          (*Server).hover,
+	"textDocument/definition":     (*Server).definition,
+	"textDocument/documentSymbol": (*Server).documentSymbol,
+}
+
+var notifications = map[string]notificationHandler{
+	"textDocument/didOpen":   (*Server).didOpen,
+	"textDocument/didChange": (*Server).didChange,
+	"textDocument/didClose":  (*Server).didClose,
+}
+
+func (srv *Server) handle(msg *message) error {
+	if msg.ID == nil {
+		handler, ok := notifications[msg.Method]
+		if !ok || !srv.initialized || srv.shutdown {
+			// Unknown notifications, such as initialized and $/ ones, are
+			// ignored as the specification asks.
+			return nil
+		}
+		return handler(srv, msg.Params)
+	}
+
+	handler, ok := requests[msg.Method]
+	switch {
+	case !ok:
+		return srv.conn.reply(msg.ID, nil, &responseError{
+			Code: codeMethodNotFound, Message: "method not found: " + msg.Method})
+	case !srv.initialized && msg.Method != "initialize":
+		return srv.conn.reply(msg.ID, nil, &responseError{
+			Code: codeServerNotInitialized, Message: "server not initialized"})
+	case srv.shutdown:
+		return srv.conn.reply(msg.ID, nil, &responseError{
+			Code: codeInvalidRequest, Message: "server is shut down"})
+	}
+
+	result, err := handler(srv, msg.Params)
+	return srv.conn.reply(msg.ID, result, err)
+}
+
+func decode(params json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (srv *Server) initialize(params json.RawMessage) (interface{}, error) {
+	if srv.initialized {
+		return nil, &responseError{Code: codeInvalidRequest, Message: "server already initialized"}
+	}
+	srv.initialized = true
+
+	return map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			"textDocumentSync": map[string]interface{}{
+				"openClose": true,
+				"change":    1, // full
+			},
+			"completionProvider":     map[string]interface{}{"triggerCharacters": []string{"."}},
+			"hoverProvider":          true,
+			"definitionProvider":     true,
+			"documentSymbolProvider": true,
+		},
+		"serverInfo": map[string]string{"name": "cathon"},
+	}, nil
+}
+
+func (srv *Server) shutdownRequest(params json.RawMessage) (interface{}, error) {
+	srv.shutdown = true
+	return nil, nil
+}
+
+func (srv *Server) didOpen(params json.RawMessage) error {
+	var p DidOpenTextDocumentParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil
+	}
+	return srv.update(newDocument(p.TextDocument.URI, p.TextDocument.Version, p.TextDocument.Text, srv.Globals))
+}
+
+func (srv *Server) didChange(params json.RawMessage) error {
+	var p DidChangeTextDocumentParams
+	if err := json.Unmarshal(params, &p); err != nil || len(p.ContentChanges) == 0 {
+		return nil
+	}
+	text := p.ContentChanges[len(p.ContentChanges)-1].Text
+	return srv.update(newDocument(p.TextDocument.URI, p.TextDocument.Version, text, srv.Globals))
+}
+
+func (srv *Server) didClose(params json.RawMessage) error {
+	var p DidCloseTextDocumentParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil
+	}
+	delete(srv.docs, p.TextDocument.URI)
+	return srv.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         p.TextDocument.URI,
+		Diagnostics: []Diagnostic{},
+	})
+}
+
+// update stores doc and publishes its diagnostics.
+func (srv *Server) update(doc *document) error {
+	srv.docs[doc.uri] = doc
+	return srv.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         doc.uri,
+		Version:     doc.version,
+		Diagnostics: doc.diagnostics(),
+	})
+}
+
+// document returns the open document named by id.
+func (srv *Server) document(id TextDocumentIdentifier) (*document, error) {
+	doc, ok := srv.docs[id.URI]
+	if !ok {
+		return nil, &responseError{Code: codeInvalidParams, Message: "document not open: " + id.URI}
+	}
+	return doc, nil
+}
+
+func (srv *Server) completion(params json.RawMessage) (interface{}, error) {
+	var p TextDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	doc, err := srv.document(p.TextDocument)
+	if err != nil {
+		return nil, err
+	}
+
+	line, column := doc.lineColumn(p.Position)
+	names := doc.info.NamesAt(line, column)
+
+	if qualifier, ok := doc.qualifierAt(line, column); ok {
+		return srv.memberCompletions(qualifier, names), nil
+	}
+
+	items := []CompletionItem{}
+	seen := make(map[string]bool)
+	add := func(item CompletionItem) {
+		if !seen[item.Label] {
+			seen[item.Label] = true
+			items = append(items, item)
+		}
+	}
+
+	for _, name := range names {
+		add(CompletionItem{Label: name.Value, Kind: CompletionVariable})
+	}
+	for _, name := range srv.Globals {
+		add(CompletionItem{Label: name, Kind: CompletionVariable})
+	}
+	for _, name := range sortedKeys(srv.builtins) {
+		kind := CompletionFunction
+		if _, ok := srv.builtins[name].(*object.Module); ok {
+			kind = CompletionModule
+		}
+		add(builtinCompletion(name, name, kind))
+	}
+	for _, word := range token.Keywords() {
+		add(CompletionItem{Label: word, Kind: CompletionKeyword})
+	}
+	return items, nil
+}
+
+// memberCompletions completes left.<member> when left names a module that
+// no binding in names shadows.
+func (srv *Server) memberCompletions(left string, names []*ast.Identifier) []CompletionItem {
+	items := []CompletionItem{}
+	for _, name := range names {
+		if name.Value == left {
+			return items
+		}
+	}
+	module, ok := srv.builtins[left].(*object.Module)
+	if !ok {
+		return items
+	}
+
+	for _, member := range sortedKeys(module.Members) {
+		kind := CompletionConstant
+		if _, ok := module.Members[member].(*object.Builtin); ok {
+			kind = CompletionFunction
+		}
+		items = append(items, builtinCompletion(member, left+"."+member, kind))
+	}
+	return items
+}
+
+func builtinCompletion(label, name string, kind int) CompletionItem {
+	item := CompletionItem{Label: label, Kind: kind}
+	if sig, ok := signatures[name]; ok {
+		item.Detail = sig.label
+		item.Documentation = &MarkupContent{Kind: "markdown", Value: sig.doc}
+	}
+	return item
+}
+
+func (srv *Server) hover(params json.RawMessage) (interface{}, error) {
+	var p TextDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	doc, err := srv.document(p.TextDocument)
+	if err != nil {
+		return nil, err
+	}
+
+	ident := doc.identifierAt(doc.lineColumn(p.Position))
+	if ident == nil {
+		return nil, nil
+	}
+	sig, ok := signatures[doc.info.Builtins[ident]]
+	if !ok {
+		return nil, nil
+	}
+
+	r := doc.tokenRange(ident.Token)
+	return Hover{
+		Contents: MarkupContent{
+			Kind:  "markdown",
+			Value: "```cathon\n" + sig.label + "\n```\n\n" + sig.doc,
+		},
+		Range: &r,
+	}, nil
+}
+
+func (srv *Server) definition(params json.RawMessage) (interface{}, error) {
+	var p TextDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	doc, err := srv.document(p.TextDocument)
+	if err != nil {
+		return nil, err
+	}
+
+	ident := doc.identifierAt(doc.lineColumn(p.Position))
+	if ident == nil {
+		return nil, nil
+	}
+	decl, ok := doc.info.Definitions[ident]
+	if !ok {
+		return nil, nil
+	}
+	return Location{URI: doc.uri, Range: doc.tokenRange(decl.Token)}, nil
+}
+
+func (srv *Server) documentSymbol(params json.RawMessage) (interface{}, error) {
+	var p DocumentSymbolParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	doc, err := srv.document(p.TextDocument)
+	if err != nil {
+		return nil, err
+	}
+	return doc.symbols(doc.program.Statements), nil
+}
+
+// symbols lists the lets in statements, with those in the bodies of
+// functions as their children.
+func (d *document) symbols(statements []ast.Statement) []DocumentSymbol {
+	symbols := []DocumentSymbol{}
+	for _, stmt := range statements {
+		let, ok := stmt.(*ast.LetStatement)
+		if !ok || let.Name == nil {
+			continue
+		}
+
+		name := d.tokenRange(let.Name.Token)
+		symbol := DocumentSymbol{
+			Name:           let.Name.Value,
+			Kind:           SymbolVariable,
+			Range:          Range{Start: d.position(let.Token.Line, let.Token.Column), End: name.End},
+			SelectionRange: name,
+		}
+		if fn, ok := let.Value.(*ast.FunctionLiteral); ok {
+			symbol.Kind = SymbolFunction
+			symbol.Range.End = d.tokenRange(fn.Body.End).End
+			symbol.Children = d.symbols(fn.Body.Statements)
+		}
+		symbols = append(symbols, symbol)
+	}
+	return symbols
+}
+
+func sortedKeys(m map[string]object.Object) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,381 @@
+package lsp
+
+import (
+	"cathon/evaluator"
+	"cathon/object"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+// testClient drives a Server in the same process, as an editor would.
+type testClient struct {
+	t      *testing.T
+	conn   *conn
+	nextID int
+	done   chan error
+
+	// pending holds notifications read while waiting for a response.
+	pending []*message
+}
+
+func startServer(t *testing.T, srv *Server) *testClient {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	c := &testClient{t: t, conn: newConn(outR, inW), done: make(chan error, 1)}
+	go func() {
+		c.done <- srv.Serve(inR, outW)
+		outW.Close()
+	}()
+	t.Cleanup(func() { inW.Close() })
+	return c
+}
+
+func (c *testClient) call(method string, params, result interface{}) *responseError {
+	c.t.Helper()
+	c.nextID++
+	id, _ := json.Marshal(c.nextID)
+	raw, _ := json.Marshal(params)
+	if err := c.conn.write(&message{ID: id, Method: method, Params: raw}); err != nil {
+		c.t.Fatalf("writing %s failed: %s", method, err)
+	}
+
+	for {
+		msg := c.read()
+		if msg.ID == nil {
+			c.pending = append(c.pending, msg)
+			continue
+		}
+		if string(msg.ID) != string(id) {
+			c.t.Fatalf("response to %s has id %s, want %s", method, msg.ID, id)
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result != nil {
+			if err := json.Unmarshal(msg.Result, result); err != nil {
+				c.t.Fatalf("bad result for %s: %s", method, err)
+			}
+		}
+		return nil
+	}
+}
+
+func (c *testClient) notify(method string, params interface{}) {
+	c.t.Helper()
+	if err := c.conn.notify(method, params); err != nil {
+		c.t.Fatalf("writing %s failed: %s", method, err)
+	}
+}
+
+func (c *testClient) read() *message {
+	c.t.Helper()
+	msg, err := c.conn.read()
+	if err != nil {
+		c.t.Fatalf("read failed: %s", err)
+	}
+	return msg
+}
+
+// diagnostics returns the next diagnostics the server publishes.
+func (c *testClient) diagnostics() PublishDiagnosticsParams {
+	c.t.Helper()
+	var msg *message
+	if len(c.pending) > 0 {
+		msg, c.pending = c.pending[0], c.pending[1:]
+	} else {
+		msg = c.read()
+	}
+	if msg.Method != "textDocument/publishDiagnostics" {
+		c.t.Fatalf("expected diagnostics, got %s", msg.Method)
+	}
+	var params PublishDiagnosticsParams
+	json.Unmarshal(msg.Params, &params)
+	return params
+}
+
+func (c *testClient) open(uri, text string) {
+	c.t.Helper()
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: uri, Version: 1, Text: text},
+	})
+}
+
+func initialized(t *testing.T, srv *Server) *testClient {
+	c := startServer(t, srv)
+	if err := c.call("initialize", map[string]interface{}{}, nil); err != nil {
+		t.Fatalf("initialize failed: %s", err.Message)
+	}
+	c.notify("initialized", struct{}{})
+	return c
+}
+
+func at(line, character int) Position { return Position{Line: line, Character: character} }
+
+func span(line, start, end int) Range { return Range{Start: at(line, start), End: at(line, end)} }
+
+func TestLifecycle(t *testing.T) {
+	c := startServer(t, &Server{})
+
+	if err := c.call("textDocument/hover", struct{}{}, nil); err == nil || err.Code != codeServerNotInitialized {
+		t.Errorf("request before initialize not refused. got=%v", err)
+	}
+
+	var result struct {
+		Capabilities map[string]interface{} `json:"capabilities"`
+	}
+	if err := c.call("initialize", map[string]interface{}{"processId": nil}, &result); err != nil {
+		t.Fatalf("initialize failed: %s", err.Message)
+	}
+	for _, capability := range []string{"textDocumentSync", "completionProvider",
+		"hoverProvider", "definitionProvider", "documentSymbolProvider"} {
+		if result.Capabilities[capability] == nil {
+			t.Errorf("capability %s missing", capability)
+		}
+	}
+
+	if err := c.call("workspace/frobnicate", struct{}{}, nil); err == nil || err.Code != codeMethodNotFound {
+		t.Errorf("unknown method not refused. got=%v", err)
+	}
+
+	if err := c.call("shutdown", nil, nil); err != nil {
+		t.Fatalf("shutdown failed: %s", err.Message)
+	}
+	c.notify("exit", nil)
+	if err := <-c.done; err != nil {
+		t.Errorf("Serve returned error after exit: %s", err)
+	}
+}
+
+func TestExitWithoutShutdown(t *testing.T) {
+	c := initialized(t, &Server{})
+	c.notify("exit", nil)
+	if err := <-c.done; err != ErrExitWithoutShutdown {
+		t.Errorf("wrong error. got=%v", err)
+	}
+}
+
+func TestMessageTooLarge(t *testing.T) {
+	var out strings.Builder
+	in := strings.NewReader("Content-Length: 9999999999999999\r\n\r\n{}")
+	if err := (&Server{}).Serve(in, &out); err != nil {
+		t.Fatalf("Serve returned error: %s", err)
+	}
+
+	msg, err := newConn(strings.NewReader(out.String()), io.Discard).read()
+	if err != nil {
+		t.Fatalf("reading the reply failed: %s", err)
+	}
+	if msg.Error == nil || msg.Error.Code != codeParseError {
+		t.Errorf("wrong reply. got=%+v", msg)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	c := initialized(t, &Server{Globals: []string{"player"}})
+
+	c.open("file:///a.cth", "let s = \"😺\"; puts(s, y, player);\nlet = 1;")
+	got := c.diagnostics()
+	if got.URI != "file:///a.cth" || got.Version != 1 {
+		t.Errorf("wrong document. got=%s version %d", got.URI, got.Version)
+	}
+
+	expected := []Diagnostic{
+		{Range: span(1, 4, 5), Severity: SeverityError, Source: "cathon",
+			Message: "expected next token to be IDENT, got = instead"},
+		{Range: span(1, 4, 5), Severity: SeverityError, Source: "cathon",
+			Message: "no parse prefix function for ="},
+		// The cat is two UTF-16 code units.
+		{Range: span(0, 22, 23), Severity: SeverityError, Code: "undefined", Source: "cathon",
+			Message: "identifier not found: y"},
+	}
+	if len(got.Diagnostics) != len(expected) {
+		t.Fatalf("wrong number of diagnostics. expected=%d, got=%+v", len(expected), got.Diagnostics)
+	}
+	for i, want := range expected {
+		if got.Diagnostics[i] != want {
+			t.Errorf("wrong diagnostic %d. expected=%+v, got=%+v", i, want, got.Diagnostics[i])
+		}
+	}
+
+	c.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": "file:///a.cth", "version": 2},
+		"contentChanges": []map[string]string{{"text": "let y = 1; puts(y)"}},
+	})
+	if got := c.diagnostics(); got.Version != 2 || len(got.Diagnostics) != 0 {
+		t.Errorf("diagnostics not cleared. got=%+v", got)
+	}
+
+	c.notify("textDocument/didClose", DidCloseTextDocumentParams{
+		TextDocument: TextDocumentIdentifier{URI: "file:///a.cth"}})
+	if got := c.diagnostics(); len(got.Diagnostics) != 0 {
+		t.Errorf("diagnostics not cleared on close. got=%+v", got)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	c := initialized(t, &Server{Globals: []string{"player"}})
+	text := "let speed = 3;\nlet move = fn(dx) {\n  \n};\nmath.\n"
+	c.open("file:///a.cth", text)
+	c.diagnostics()
+
+	complete := func(pos Position) map[string]CompletionItem {
+		var items []CompletionItem
+		err := c.call("textDocument/completion", TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///a.cth"},
+			Position:     pos,
+		}, &items)
+		if err != nil {
+			t.Fatalf("completion failed: %s", err.Message)
+		}
+		byLabel := map[string]CompletionItem{}
+		for _, item := range items {
+			byLabel[item.Label] = item
+		}
+		return byLabel
+	}
+
+	inside := complete(at(2, 2))
+	for label, kind := range map[string]int{
+		"dx": CompletionVariable, "speed": CompletionVariable, "move": CompletionVariable,
+		"player": CompletionVariable, "len": CompletionFunction, "math": CompletionModule,
+		"fn": CompletionKeyword, "return": CompletionKeyword,
+	} {
+		if item, ok := inside[label]; !ok || item.Kind != kind {
+			t.Errorf("completion %s missing or wrong kind. got=%+v", label, item)
+		}
+	}
+	if inside["len"].Detail != "len(value)" {
+		t.Errorf("wrong detail for len. got=%q", inside["len"].Detail)
+	}
+
+	if _, ok := complete(at(4, 0))["dx"]; ok {
+		t.Errorf("parameter completed outside its function")
+	}
+
+	members := complete(at(4, 5))
+	if item := members["sqrt"]; item.Kind != CompletionFunction || item.Detail != "math.sqrt(x)" {
+		t.Errorf("wrong completion for math.sqrt. got=%+v", item)
+	}
+	if item := members["pi"]; item.Kind != CompletionConstant {
+		t.Errorf("wrong completion for math.pi. got=%+v", item)
+	}
+	if _, ok := members["len"]; ok {
+		t.Errorf("builtins completed after a module")
+	}
+}
+
+func TestHoverAndDefinition(t *testing.T) {
+	c := initialized(t, &Server{})
+	text := "let xs = [1, 2];\nlet f = fn(n) {\n  len(xs) + math.sqrt(n)\n};\nf(4)"
+	c.open("file:///a.cth", text)
+	c.diagnostics()
+
+	position := func(pos Position) TextDocumentPositionParams {
+		return TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///a.cth"},
+			Position:     pos,
+		}
+	}
+
+	hovers := []struct {
+		pos      Position
+		expected string
+	}{
+		{at(2, 3), "```cathon\nlen(value)\n```\n\nReturns the number of characters of a STRING or of elements of an ARRAY."},
+		{at(2, 19), "```cathon\nmath.sqrt(x)\n```\n\nReturns the square root of x."},
+		{at(2, 14), "```cathon\nmath\n```\n\nNumbers and random numbers, such as math.sqrt."},
+	}
+	for _, tt := range hovers {
+		var hover *Hover
+		if err := c.call("textDocument/hover", position(tt.pos), &hover); err != nil {
+			t.Fatalf("hover failed: %s", err.Message)
+		}
+		if hover == nil || hover.Contents.Value != tt.expected {
+			t.Errorf("wrong hover at %+v. expected=%q, got=%+v", tt.pos, tt.expected, hover)
+		}
+	}
+
+	var hover *Hover
+	c.call("textDocument/hover", position(at(2, 7)), &hover)
+	if hover != nil {
+		t.Errorf("hover over a let binding. got=%+v", hover)
+	}
+
+	definitions := []struct {
+		pos      Position
+		expected *Range
+	}{
+		{at(2, 7), &Range{Start: at(0, 4), End: at(0, 6)}},
+		{at(2, 23), &Range{Start: at(1, 11), End: at(1, 12)}},
+		{at(4, 0), &Range{Start: at(1, 4), End: at(1, 5)}},
+		{at(2, 3), nil},
+	}
+	for _, tt := range definitions {
+		var loc *Location
+		if err := c.call("textDocument/definition", position(tt.pos), &loc); err != nil {
+			t.Fatalf("definition failed: %s", err.Message)
+		}
+		switch {
+		case tt.expected == nil && loc != nil:
+			t.Errorf("unexpected definition at %+v. got=%+v", tt.pos, loc)
+		case tt.expected != nil && (loc == nil || loc.Range != *tt.expected || loc.URI != "file:///a.cth"):
+			t.Errorf("wrong definition at %+v. expected=%+v, got=%+v", tt.pos, tt.expected, loc)
+		}
+	}
+}
+
+func TestDocumentSymbol(t *testing.T) {
+	c := initialized(t, &Server{})
+	text := "let speed = 3;\nlet move = fn(dx) {\n  let next = dx * speed;\n  next\n};\nmove(1)"
+	c.open("file:///a.cth", text)
+	c.diagnostics()
+
+	var symbols []DocumentSymbol
+	err := c.call("textDocument/documentSymbol", DocumentSymbolParams{
+		TextDocument: TextDocumentIdentifier{URI: "file:///a.cth"}}, &symbols)
+	if err != nil {
+		t.Fatalf("documentSymbol failed: %s", err.Message)
+	}
+
+	var out strings.Builder
+	var write func(symbols []DocumentSymbol, indent string)
+	write = func(symbols []DocumentSymbol, indent string) {
+		for _, s := range symbols {
+			out.WriteString(indent + s.Name)
+			if s.Kind == SymbolFunction {
+				out.WriteString("()")
+			}
+			out.WriteString("\n")
+			write(s.Children, indent+"  ")
+		}
+	}
+	write(symbols, "")
+
+	if expected := "speed\nmove()\n  next\n"; out.String() != expected {
+		t.Errorf("wrong symbols. expected=%q, got=%q", expected, out.String())
+	}
+	if move := symbols[1]; move.Range != span(1, 0, 4).withEnd(at(4, 1)) ||
+		move.SelectionRange != span(1, 4, 8) {
+		t.Errorf("wrong ranges for move. got=%+v", move)
+	}
+}
+
+func (r Range) withEnd(end Position) Range { return Range{Start: r.Start, End: end} }
+
+func TestSignaturesCoverBuiltins(t *testing.T) {
+	for name, builtin := range evaluator.New().Builtins() {
+		if _, ok := signatures[name]; !ok {
+			t.Errorf("no signature for %s", name)
+		}
+		if module, ok := builtin.(*object.Module); ok {
+			for member := range module.Members {
+				if _, ok := signatures[name+"."+member]; !ok {
+					t.Errorf("no signature for %s.%s", name, member)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package lsp
+
+// signature is how hover and completion describe a builtin.
+type signature struct {
+	label string
+	doc   string
+}
+
+// signatures describe the builtins, with module members by their qualified
+// names.
+var signatures = map[string]signature{
+	"len":     {"len(value)", "Returns the number of characters of a STRING or of elements of an ARRAY."},
+	"first":   {"first(array)", "Returns the first element of array, or null if it is empty."},
+	"last":    {"last(array)", "Returns the last element of array, or null if it is empty."},
+	"rest":    {"rest(array)", "Returns a new array without the first element, or null if it is empty."},
+	"push":    {"push(array, value)", "Returns a new array with value added to the end."},
+	"zip":     {"zip(array, ...)", "Returns an array of arrays pairing up the elements at each index."},
+	"keys":    {"keys(hash)", "Returns the keys of hash in insertion order."},
+	"values":  {"values(hash)", "Returns the values of hash in insertion order."},
+	"entries": {"entries(hash)", "Returns the [key, value] pairs of hash in insertion order."},
+	"has":     {"has(hash, key)", "Reports whether hash has key."},
+	"delete":  {"delete(hash, key)", "Returns a new hash without key."},
+	"merge":   {"merge(hash, ...)", "Returns a new hash with the pairs of every argument; later ones win."},
+	"bytes":   {"bytes(string)", "Returns the UTF-8 bytes of string as an array of integers."},
+	"runes":   {"runes(string)", "Returns the code points of string as an array of integers."},
+	"sprintf": {"sprintf(format, args...)", "Formats args according to format, as Go's fmt does."},
+	"printf":  {"printf(format, args...)", "Formats args according to format and writes the result."},
+	"puts":    {"puts(values...)", "Writes each value on its own line."},
+	"type":    {"type(value)", "Returns the type of value as a string, such as \"INTEGER\"."},
+
+	"str":   {"str(value)", "Converts value to a STRING."},
+	"int":   {"int(value)", "Converts value to an INTEGER."},
+	"float": {"float(value)", "Converts value to a FLOAT."},
+	"bool":  {"bool(value)", "Converts value to a BOOLEAN."},
+
+	"isInt":      {"isInt(value)", "Reports whether value is an INTEGER."},
+	"isFloat":    {"isFloat(value)", "Reports whether value is a FLOAT."},
+	"isNumber":   {"isNumber(value)", "Reports whether value is an INTEGER or a FLOAT."},
+	"isString":   {"isString(value)", "Reports whether value is a STRING."},
+	"isBool":     {"isBool(value)", "Reports whether value is a BOOLEAN."},
+	"isArray":    {"isArray(value)", "Reports whether value is an ARRAY."},
+	"isHash":     {"isHash(value)", "Reports whether value is a HASH."},
+	"isNull":     {"isNull(value)", "Reports whether value is null."},
+	"isFunction": {"isFunction(value)", "Reports whether value can be called."},
+
+	"map":    {"map(array, fn)", "Returns a new array of fn(element) for each element."},
+	"filter": {"filter(array, fn)", "Returns the elements for which fn(element) is truthy."},
+	"reduce": {"reduce(array, fn, initial?)", "Combines the elements with fn(acc, element), starting from initial or the first element."},
+	"sort":   {"sort(array, less?)", "Returns a sorted copy of array, ordered by less(a, b) if given."},
+	"find":   {"find(array, fn)", "Returns the first element for which fn(element) is truthy, or null."},
+	"any":    {"any(array, fn)", "Reports whether fn(element) is truthy for some element."},
+	"all":    {"all(array, fn)", "Reports whether fn(element) is truthy for every element."},
+
+	"string":            {"string", "Functions on strings, such as string.split."},
+	"string.split":      {"string.split(s, sep?)", "Splits s around sep, or around whitespace if sep is left out."},
+	"string.join":       {"string.join(array, sep)", "Joins the strings in array with sep between them."},
+	"string.trim":       {"string.trim(s, cutset?)", "Removes the characters in cutset, or whitespace, from both ends of s."},
+	"string.upper":      {"string.upper(s)", "Returns s in upper case."},
+	"string.lower":      {"string.lower(s)", "Returns s in lower case."},
+	"string.replace":    {"string.replace(s, old, new)", "Replaces every old in s with new."},
+	"string.contains":   {"string.contains(s, sub)", "Reports whether sub is in s."},
+	"string.startsWith": {"string.startsWith(s, prefix)", "Reports whether s starts with prefix."},
+	"string.endsWith":   {"string.endsWith(s, suffix)", "Reports whether s ends with suffix."},
+	"string.indexOf":    {"string.indexOf(s, sub)", "Returns the index of the first sub in s, or -1."},
+	"string.repeat":     {"string.repeat(s, count)", "Returns s repeated count times."},
+	"string.padLeft":    {"string.padLeft(s, width, pad?)", "Pads s on the left to width characters with pad, or spaces."},
+	"string.padRight":   {"string.padRight(s, width, pad?)", "Pads s on the right to width characters with pad, or spaces."},
+
+	"math":        {"math", "Numbers and random numbers, such as math.sqrt."},
+	"math.pi":     {"math.pi", "The ratio of a circle's circumference to its diameter."},
+	"math.abs":    {"math.abs(x)", "Returns the absolute value of x."},
+	"math.min":    {"math.min(x, ...)", "Returns the smallest argument."},
+	"math.max":    {"math.max(x, ...)", "Returns the largest argument."},
+	"math.clamp":  {"math.clamp(x, lo, hi)", "Returns x limited to between lo and hi."},
+	"math.floor":  {"math.floor(x)", "Rounds x down to an INTEGER."},
+	"math.ceil":   {"math.ceil(x)", "Rounds x up to an INTEGER."},
+	"math.round":  {"math.round(x)", "Rounds x to the nearest INTEGER, halves away from zero."},
+	"math.sqrt":   {"math.sqrt(x)", "Returns the square root of x."},
+	"math.sin":    {"math.sin(x)", "Returns the sine of x radians."},
+	"math.cos":    {"math.cos(x)", "Returns the cosine of x radians."},
+	"math.atan2":  {"math.atan2(y, x)", "Returns the angle of the point (x, y) in radians."},
+	"math.lerp":   {"math.lerp(a, b, t)", "Returns the value t of the way from a to b."},
+	"math.random": {"math.random(n?, hi?)", "Returns a FLOAT in [0, 1), an INTEGER in [0, n), or an INTEGER in [n, hi]."},
+	"math.seed":   {"math.seed(n)", "Seeds the random numbers of this interpreter."},
+
+	"json":           {"json", "JSON encoding, such as json.parse."},
+	"json.parse":     {"json.parse(s)", "Parses the JSON document s."},
+	"json.stringify": {"json.stringify(value, indent?)", "Encodes value as JSON, indented by indent spaces or by the indent string."},
+}
@@ -0,0 +1,133 @@
+package lsp
+
+import (
+	"bytes"
+	"cathon/evaluator"
+	"cathon/lexer"
+	"cathon/object"
+	"cathon/parser"
+	"testing"
+)
+
+// signatureExamples show what each signature documents, so the docs can't
+// drift from the builtins. result is the Inspect of the value and output
+// what puts and printf write.
+var signatureExamples = map[string][]struct {
+	input  string
+	result string
+	output string
+}{
+	"len":     {{`len("고양이")`, "3", ""}, {`len([1, 2])`, "2", ""}},
+	"first":   {{`first([1, 2])`, "1", ""}, {`first([])`, "null", ""}},
+	"last":    {{`last([1, 2])`, "2", ""}, {`last([])`, "null", ""}},
+	"rest":    {{`rest([1, 2, 3])`, "[2, 3]", ""}, {`rest([])`, "null", ""}},
+	"push":    {{`let a = [1]; push(a, 2); a`, "[1]", ""}, {`push([1], 2)`, "[1, 2]", ""}},
+	"zip":     {{`zip([1, 2], ["a", "b"])`, "[[1, a], [2, b]]", ""}},
+	"keys":    {{`keys({"b": 1, "a": 2})`, "[b, a]", ""}},
+	"values":  {{`values({"b": 1, "a": 2})`, "[1, 2]", ""}},
+	"entries": {{`entries({"b": 1, "a": 2})`, "[[b, 1], [a, 2]]", ""}},
+	"has":     {{`has({"a": 1}, "a")`, "true", ""}, {`has({"a": 1}, "b")`, "false", ""}},
+	"delete":  {{`let h = {"a": 1, "b": 2}; delete(h, "a"); h`, "{a: 1, b: 2}", ""}, {`delete({"a": 1, "b": 2}, "a")`, "{b: 2}", ""}},
+	"merge":   {{`merge({"a": 1}, {"a": 2, "b": 3})`, "{a: 2, b: 3}", ""}},
+	"bytes":   {{`bytes("가")`, "[234, 176, 128]", ""}},
+	"runes":   {{`runes("ab")`, "[97, 98]", ""}, {`runes("가")`, "[44032]", ""}},
+	"sprintf": {{`sprintf("%d %s", 1, "cat")`, "1 cat", ""}},
+	"printf":  {{`printf("%d %s", 1, "cat")`, "null", "1 cat"}},
+	"puts":    {{`puts(1, "cat")`, "null", "1\ncat\n"}},
+	"type":    {{`type(1)`, "INTEGER", ""}},
+
+	"str":   {{`str(1.5)`, "1.5", ""}},
+	"int":   {{`int("12")`, "12", ""}, {`int(2.7)`, "2", ""}},
+	"float": {{`float(2)`, "2.0", ""}},
+	"bool":  {{`bool(0)`, "true", ""}},
+
+	"isInt":      {{`isInt(1)`, "true", ""}, {`isInt(1.0)`, "false", ""}},
+	"isFloat":    {{`isFloat(1.0)`, "true", ""}, {`isFloat(1)`, "false", ""}},
+	"isNumber":   {{`isNumber(1)`, "true", ""}, {`isNumber(1.5)`, "true", ""}, {`isNumber("1")`, "false", ""}},
+	"isString":   {{`isString("")`, "true", ""}},
+	"isBool":     {{`isBool(false)`, "true", ""}},
+	"isArray":    {{`isArray([])`, "true", ""}},
+	"isHash":     {{`isHash({})`, "true", ""}},
+	"isNull":     {{`isNull(first([]))`, "true", ""}},
+	"isFunction": {{`isFunction(len)`, "true", ""}, {`isFunction(fn() {})`, "true", ""}},
+
+	"map":    {{`map([1, 2], fn(x) { x * 2 })`, "[2, 4]", ""}},
+	"filter": {{`filter([1, 2, 3], fn(x) { x > 1 })`, "[2, 3]", ""}},
+	"reduce": {{`reduce([1, 2, 3], fn(a, b) { a + b })`, "6", ""}, {`reduce([1, 2, 3], fn(a, b) { a + b }, 10)`, "16", ""}},
+	"sort":   {{`sort([3, 1, 2])`, "[1, 2, 3]", ""}, {`sort([3, 1, 2], fn(a, b) { a > b })`, "[3, 2, 1]", ""}},
+	"find":   {{`find([1, 2, 3], fn(x) { x > 1 })`, "2", ""}, {`find([1], fn(x) { x > 1 })`, "null", ""}},
+	"any":    {{`any([1, 2], fn(x) { x > 1 })`, "true", ""}},
+	"all":    {{`all([1, 2], fn(x) { x > 1 })`, "false", ""}},
+
+	"string":            {{`type(string)`, "MODULE", ""}},
+	"string.split":      {{`string.split("a,b", ",")`, "[a, b]", ""}, {`string.split(" a  b ")`, "[a, b]", ""}},
+	"string.join":       {{`string.join(["a", "b"], "-")`, "a-b", ""}},
+	"string.trim":       {{`string.trim("xax", "x")`, "a", ""}, {`string.trim(" a ")`, "a", ""}},
+	"string.upper":      {{`string.upper("a")`, "A", ""}},
+	"string.lower":      {{`string.lower("A")`, "a", ""}},
+	"string.replace":    {{`string.replace("aba", "a", "c")`, "cbc", ""}},
+	"string.contains":   {{`string.contains("cat", "a")`, "true", ""}},
+	"string.startsWith": {{`string.startsWith("cat", "c")`, "true", ""}},
+	"string.endsWith":   {{`string.endsWith("cat", "t")`, "true", ""}},
+	"string.indexOf":    {{`string.indexOf("cat", "t")`, "2", ""}, {`string.indexOf("cat", "z")`, "-1", ""}},
+	"string.repeat":     {{`string.repeat("ab", 2)`, "abab", ""}},
+	"string.padLeft":    {{`string.padLeft("a", 3, "0")`, "00a", ""}, {`string.padLeft("a", 3)`, "  a", ""}},
+	"string.padRight":   {{`string.padRight("고", 3, "-")`, "고--", ""}},
+
+	"math":        {{`type(math)`, "MODULE", ""}},
+	"math.pi":     {{`math.pi`, "3.141592653589793", ""}},
+	"math.abs":    {{`math.abs(-2)`, "2", ""}},
+	"math.min":    {{`math.min(3, 1, 2)`, "1", ""}},
+	"math.max":    {{`math.max(3, 1, 2)`, "3", ""}},
+	"math.clamp":  {{`math.clamp(5, 0, 3)`, "3", ""}},
+	"math.floor":  {{`math.floor(2.7)`, "2", ""}},
+	"math.ceil":   {{`math.ceil(2.1)`, "3", ""}},
+	"math.round":  {{`math.round(2.5)`, "3", ""}, {`math.round(-2.5)`, "-3", ""}},
+	"math.sqrt":   {{`math.sqrt(4)`, "2.0", ""}},
+	"math.sin":    {{`math.sin(0)`, "0.0", ""}},
+	"math.cos":    {{`math.cos(0)`, "1.0", ""}},
+	"math.atan2":  {{`math.atan2(1, 1) * 4`, "3.141592653589793", ""}},
+	"math.lerp":   {{`math.lerp(0, 10, 0.5)`, "5.0", ""}},
+	"math.random": {{`type(math.random())`, "FLOAT", ""}, {`math.random(1)`, "0", ""}, {`math.random(2, 2)`, "2", ""}},
+	"math.seed":   {{`math.seed(1); let a = math.random(); math.seed(1); a == math.random()`, "true", ""}},
+
+	"json":           {{`type(json)`, "MODULE", ""}},
+	"json.parse":     {{`json.parse("[1, 2.5]")`, "[1, 2.5]", ""}},
+	"json.stringify": {{`json.stringify({"a": [1]})`, `{"a":[1]}`, ""}, {`json.stringify([1], 1)`, "[\n 1\n]", ""}},
+}
+
+func TestSignatureExamples(t *testing.T) {
+	for name := range signatures {
+		if len(signatureExamples[name]) == 0 {
+			t.Errorf("no example for the signature of %s", name)
+		}
+	}
+
+	for name, examples := range signatureExamples {
+		if _, ok := signatures[name]; !ok {
+			t.Errorf("example for %s, which has no signature", name)
+		}
+
+		for _, ex := range examples {
+			p := parser.New(lexer.New(ex.input))
+			program := p.ParseProgram()
+			if len(p.Errors()) != 0 {
+				t.Errorf("%s: %q has parser errors: %v", name, ex.input, p.Errors())
+				continue
+			}
+
+			var out bytes.Buffer
+			interp := evaluator.New()
+			interp.Out = &out
+			result := interp.Eval(program, object.NewEnvironment())
+			if result == nil || result.Inspect() != ex.result || out.String() != ex.output {
+				got := "nil"
+				if result != nil {
+					got = result.Inspect()
+				}
+				t.Errorf("%s: %q gave %q and wrote %q, want %q and %q",
+					name, ex.input, got, out.String(), ex.result, ex.output)
+			}
+		}
+	}
+}
@@ -1,19 +1,359 @@
 package main
 
 import (
+	"cathon/ast"
+	"cathon/checker"
+	"cathon/dap"
+	"cathon/debugger"
+	"cathon/evaluator"
+	"cathon/lexer"
+	"cathon/lsp"
+	"cathon/object"
+	"cathon/parser"
 	"cathon/repl"
+	"cathon/token"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/user"
+	"runtime/debug"
+	"strings"
+)
+
+// version is set at build time with -ldflags "-X main.version=...". Builds
+// without it report the module version, if any.
+var version = "dev"
+
+const usage = `Usage:
+  cathon [repl]                 start the REPL
+  cathon run <file> [args...]   run a script; args are in the array args
+  cathon run [- [args...]]      run a script read from standard input
+  cathon <file> [args...]       the same as cathon run, for #! lines
+  cathon tokens [file]          print the tokens of a script
+  cathon ast [file]             print how a script parses
+  cathon debug <file> [args...] run a script under the debugger
+  cathon check [flags] [files]  report likely mistakes without running
+  cathon lsp                    serve the Language Server Protocol on stdio
+  cathon dap [-listen addr]     serve the Debug Adapter Protocol on stdio,
+                                or on a localhost TCP address
+  cathon version                print the version
+
+Scripts exit with their value when it is an INTEGER from 0 to 255, with
+1 after an uncaught error or for other integers, and with 2 when they
+don't parse. check prints one file:line:column: severity: message (code)
+line per finding, or a JSON array with -json, and exits with 1 if any
+finding is an error; parser errors are findings with the code syntax.
+debug stops before the first line and reads commands such as break, step
+and print from standard input; type help there for the list.
+`
+
+// Exit statuses besides a script's own.
+const (
+	exitError = 1
+	exitUsage = 2
 )
 
 func main() {
-	user, err := user.Current()
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command in args and returns the exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		return startREPL(stdin, stdout)
+	}
+
+	switch args[0] {
+	case "repl":
+		return startREPL(stdin, stdout)
+	case "run":
+		return runScript(args[1:], stdin, stdout, stderr)
+	case "tokens":
+		return printTokens(args[1:], stdin, stdout, stderr)
+	case "ast":
+		return printAST(args[1:], stdin, stdout, stderr)
+	case "debug":
+		return debugScript(args[1:], stdin, stdout, stderr)
+	case "check":
+		return check(args[1:], stdin, stdout, stderr)
+	case "lsp":
+		srv := &lsp.Server{Globals: []string{"args"}}
+		if err := srv.Serve(stdin, stdout); err != nil {
+			fmt.Fprintf(stderr, "cathon: %s\n", err)
+			return exitError
+		}
+		return 0
+	case "dap":
+		return serveDAP(args[1:], stdin, stdout, stderr)
+	case "version":
+		fmt.Fprintf(stdout, "cathon %s\n", versionString())
+		return 0
+	case "help", "-h", "-help", "--help":
+		io.WriteString(stdout, usage)
+		return 0
+	default:
+		// A script starting with "#!/usr/bin/env cathon" runs as
+		// cathon <file> [args...].
+		if info, err := os.Stat(args[0]); err == nil && !info.IsDir() {
+			return runScript(args, stdin, stdout, stderr)
+		}
+		fmt.Fprintf(stderr, "cathon: unknown command %q\n\n%s", args[0], usage)
+		return exitUsage
+	}
+}
+
+func startREPL(stdin io.Reader, stdout io.Writer) int {
+	name := "there"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	fmt.Fprintf(stdout, "Hello %s! This is the cathon programming language!\n", name)
+	fmt.Fprintf(stdout, "Feel free to type in commands\n")
+	repl.Start(stdin, stdout)
+	return 0
+}
+
+func runScript(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	src, scriptArgs, err := readSource(args, stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "cathon: %s\n", err)
+		return exitUsage
+	}
+
+	program, ok := parse(src, stderr)
+	if !ok {
+		return exitUsage
+	}
+
+	interp := evaluator.New()
+	interp.Out = stdout
+	return exitStatus(interp.Eval(program, scriptEnv(scriptArgs)), stderr)
+}
+
+// debugScript runs the script named in args under the debugger, which reads
+// its commands from stdin.
+func debugScript(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintf(stderr, "cathon: debug needs a script file\n")
+		return exitUsage
+	}
+	src, err := os.ReadFile(args[0])
+	if err != nil {
+		fmt.Fprintf(stderr, "cathon: %s\n", err)
+		return exitUsage
+	}
+
+	program, ok := parse(string(src), stderr)
+	if !ok {
+		return exitUsage
+	}
+
+	interp := evaluator.New()
+	interp.Out = stdout
+	d := debugger.New(string(src), stdin, stdout)
+	return exitStatus(d.Run(interp, program, scriptEnv(args[1:])), stderr)
+}
+
+// serveDAP serves one debugging session on stdin and stdout, or with
+// -listen, a session for each connection to a loopback address.
+func serveDAP(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("dap", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	listen := flags.String("listen", "", "serve on the loopback `address` such as 127.0.0.1:4711")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if *listen == "" {
+		if err := (&dap.Server{}).Serve(stdin, stdout); err != nil {
+			fmt.Fprintf(stderr, "cathon: %s\n", err)
+			return exitError
+		}
+		return 0
+	}
+
+	l, err := repl.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Fprintf(stderr, "cathon: %s\n", err)
+		return exitUsage
+	}
+	fmt.Fprintf(stderr, "cathon: debug adapter listening on %s\n", l.Addr())
+	if err := dap.ServeConns(l); err != nil {
+		fmt.Fprintf(stderr, "cathon: %s\n", err)
+		return exitError
+	}
+	return 0
+}
+
+// scriptEnv returns the environment scripts start in, with their arguments
+// in the array args.
+func scriptEnv(scriptArgs []string) *object.Environment {
+	elements := make([]object.Object, len(scriptArgs))
+	for i, arg := range scriptArgs {
+		elements[i] = &object.String{Value: arg}
+	}
+	env := object.NewEnvironment()
+	env.Set("args", &object.Array{Elements: elements})
+	return env
+}
+
+// exitStatus returns the exit status for the result of a script, printing
+// it first if it is an error.
+func exitStatus(result object.Object, stderr io.Writer) int {
+	status, err := evaluator.ExitStatus(result)
+	if err != nil {
+		fmt.Fprintln(stderr, err.Inspect())
+	}
+	return status
+}
+
+func printTokens(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	src, _, err := readSource(args, stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "cathon: %s\n", err)
+		return exitUsage
+	}
+
+	l := lexer.New(src)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		fmt.Fprintf(stdout, "%-9s %q\n", tok.Type, tok.Literal)
+	}
+	return 0
+}
+
+func printAST(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	src, _, err := readSource(args, stdin)
 	if err != nil {
-		panic(err)
+		fmt.Fprintf(stderr, "cathon: %s\n", err)
+		return exitUsage
+	}
+
+	program, ok := parse(src, stderr)
+	if !ok {
+		return exitUsage
+	}
+	for _, stmt := range program.Statements {
+		fmt.Fprintln(stdout, stmt.String())
+	}
+	return 0
+}
+
+// fileDiagnostic is a checker.Diagnostic with the file it was found in, as
+// check -json prints it.
+type fileDiagnostic struct {
+	File string `json:"file"`
+	checker.Diagnostic
+}
+
+func check(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	asJSON := flags.Bool("json", false, "print the findings as a JSON array")
+	globals := flags.String("globals", "", "comma-separated `names` the host defines besides args")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	names := []string{"args"}
+	if *globals != "" {
+		names = append(names, strings.Split(*globals, ",")...)
+	}
+
+	files := flags.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	status := 0
+	found := []fileDiagnostic{}
+	for _, file := range files {
+		src, _, err := readSource([]string{file}, stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "cathon: %s\n", err)
+			return exitUsage
+		}
+		if file == "-" {
+			file = "<stdin>"
+		}
+
+		// A file that doesn't parse gets its parser errors as findings,
+		// since checking what parsed would report the rest of it as
+		// mistakes too.
+		p := parser.New(lexer.New(src))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			for i, msg := range p.Errors() {
+				tok := p.ErrorTokens()[i]
+				found = append(found, fileDiagnostic{File: file, Diagnostic: checker.Diagnostic{
+					Line:     tok.Line,
+					Column:   tok.Column,
+					Severity: checker.Error,
+					Code:     checker.SYNTAX,
+					Message:  msg,
+				}})
+			}
+			status = exitError
+			continue
+		}
+
+		for _, d := range checker.Check(program, names...) {
+			found = append(found, fileDiagnostic{File: file, Diagnostic: d})
+			if d.Severity == checker.Error {
+				status = exitError
+			}
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		enc.Encode(found)
+		return status
+	}
+	for _, d := range found {
+		fmt.Fprintf(stdout, "%s:%s\n", d.File, d.Diagnostic)
+	}
+	return status
+}
+
+// readSource reads the script named by args[0], or standard input when
+// args is empty or args[0] is "-", and returns it with the rest of args.
+func readSource(args []string, stdin io.Reader) (string, []string, error) {
+	if len(args) == 0 || args[0] == "-" {
+		if len(args) > 0 {
+			args = args[1:]
+		}
+		src, err := io.ReadAll(stdin)
+		return string(src), args, err
+	}
+
+	src, err := os.ReadFile(args[0])
+	return string(src), args[1:], err
+}
+
+// parse parses src, printing the errors to stderr if there are any.
+func parse(src string, stderr io.Writer) (*ast.Program, bool) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		fmt.Fprintln(stderr, "parser errors:")
+		for _, msg := range p.Errors() {
+			fmt.Fprintf(stderr, "\t%s\n", msg)
+		}
+		return nil, false
+	}
+	return program, true
+}
+
+func versionString() string {
+	if version != "dev" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
 	}
-	fmt.Printf("Hello %s! This is the cathon programming language!\n",
-		user.Username)
-	fmt.Printf("Feel free to type in commands\n")
-	repl.Start(os.Stdin, os.Stdout)
+	return version
 }
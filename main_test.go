@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestMain runs the test binary as cathon when it is invoked by that name,
+// so TestShebang can put it on PATH.
+func TestMain(m *testing.M) {
+	if filepath.Base(os.Args[0]) == "cathon" {
+		main()
+	}
+	os.Exit(m.Run())
+}
+
+func TestRun(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "script.cth")
+	src := "#!/usr/bin/env cathon\nputs(len(args));\nreturn len(args) + 3;\n"
+	if err := os.WriteFile(script, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	broken := filepath.Join(t.TempDir(), "broken.cth")
+	if err := os.WriteFile(broken, []byte("puts(1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args   []string
+		stdin  string
+		status int
+		stdout string
+		stderr string
+	}{
+		{[]string{"run", script, "a", "b"}, "", 5, "2\n", ""},
+		{[]string{"run", script}, "", 3, "0\n", ""},
+		{[]string{script, "a"}, "", 4, "1\n", ""},
+		{[]string{"run"}, "puts(1 + 2); 0", 0, "3\n", ""},
+		{[]string{"run", "-", "x"}, "puts(args[0]); 7", 7, "x\n", ""},
+		{[]string{"run", "-"}, `"done"`, 0, "", ""},
+		{[]string{"run", "-"}, "puts(1); 1 + true; puts(2)", 1, "1\n",
+			"ERROR: type mismatch: INTEGER + BOOLEAN\n"},
+		{[]string{"run", "-"}, "255", 255, "", ""},
+		{[]string{"run", "-"}, "300", 1, "", "ERROR: exit status out of range: 300\n"},
+		{[]string{"run", "-"}, "-1", 1, "", "ERROR: exit status out of range: -1\n"},
+		{[]string{"run", "-"}, "let = 1;", 2, "", "parser errors:\n"},
+		{[]string{"run", filepath.Join(t.TempDir(), "missing.cth")}, "", 2, "", "cathon: "},
+		{[]string{"tokens"}, "let x = 1;", 0,
+			"LET       \"let\"\nIDENT     \"x\"\n=         \"=\"\nINT       \"1\"\n;         \";\"\n", ""},
+		{[]string{"ast", "-"}, "let x = 1 + 2 * 3", 0, "let x = (1 + (2 * 3));\n", ""},
+		{[]string{"check"}, "let x = 1;\nputs(args, y)", 1,
+			"<stdin>:1:5: warning: x is declared but never used (unused)\n" +
+				"<stdin>:2:12: error: identifier not found: y (undefined)\n", ""},
+		{[]string{"check", "-globals", "y,z", script}, "", 0, "", ""},
+		{[]string{"check", "-json", "-"}, "len()", 1,
+			"[\n  {\n    \"file\": \"<stdin>\",\n    \"line\": 1,\n    \"column\": 1,\n" +
+				"    \"severity\": \"error\",\n    \"code\": \"arity\",\n" +
+				"    \"message\": \"wrong number of arguments to len. got=0, want=1\"\n  }\n]\n", ""},
+		{[]string{"check", "-json"}, "1", 0, "[]\n", ""},
+		{[]string{"check"}, "let = 1;", 1,
+			"<stdin>:1:5: error: expected next token to be IDENT, got = instead (syntax)\n" +
+				"<stdin>:1:5: error: no parse prefix function for = (syntax)\n", ""},
+		{[]string{"check", "-json", broken, "-"}, "puts(y)", 1,
+			"[\n  {\n    \"file\": \"" + broken + "\",\n    \"line\": 1,\n    \"column\": 7,\n" +
+				"    \"severity\": \"error\",\n    \"code\": \"syntax\",\n" +
+				"    \"message\": \"expected next token to be ), got EOF instead\"\n  },\n" +
+				"  {\n    \"file\": \"<stdin>\",\n    \"line\": 1,\n    \"column\": 6,\n" +
+				"    \"severity\": \"error\",\n    \"code\": \"undefined\",\n" +
+				"    \"message\": \"identifier not found: y\"\n  }\n]\n", ""},
+		{[]string{"lsp"}, "", 0, "", ""},
+		{[]string{"dap"}, "", 0, "", ""},
+		{[]string{"dap", "-listen", "10.1.2.3:4711"}, "", 2, "", "cathon: repl: 10.1.2.3 is not a loopback address\n"},
+		{[]string{"debug", script, "a", "b"}, "continue\n", 5, "   2  puts(len(args));\n(debug) 2\n", ""},
+		{[]string{"debug", script}, "quit\n", 0, "   2  puts(len(args));\n(debug) ", ""},
+		{[]string{"debug"}, "", 2, "", "cathon: debug needs a script file\n"},
+		{[]string{"version"}, "", 0, "cathon dev\n", ""},
+		{[]string{"frobnicate"}, "", 2, "", "cathon: unknown command \"frobnicate\"\n"},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		status := run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+		if status != tt.status {
+			t.Errorf("wrong status for %v. expected=%d, got=%d (stderr %q)",
+				tt.args, tt.status, status, stderr.String())
+		}
+		if stdout.String() != tt.stdout {
+			t.Errorf("wrong stdout for %v. expected=%q, got=%q", tt.args, tt.stdout, stdout.String())
+		}
+		if !strings.HasPrefix(stderr.String(), tt.stderr) || (tt.stderr == "" && stderr.Len() != 0) {
+			t.Errorf("wrong stderr for %v. expected prefix %q, got=%q", tt.args, tt.stderr, stderr.String())
+		}
+	}
+}
+
+func TestShebang(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("#! lines need a Unix kernel")
+	}
+	if _, err := os.Stat("/usr/bin/env"); err != nil {
+		t.Skip("no /usr/bin/env")
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bin := t.TempDir()
+	if err := os.Symlink(exe, filepath.Join(bin, "cathon")); err != nil {
+		t.Fatal(err)
+	}
+
+	script := filepath.Join(t.TempDir(), "script.cth")
+	src := "#!/usr/bin/env cathon\nputs(args);\nreturn len(args) + 3;\n"
+	if err := os.WriteFile(script, []byte(src), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(script, "a", "b")
+	cmd.Env = append(os.Environ(), "PATH="+bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	out, err := cmd.Output()
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		t.Fatalf("running the script: %v (output %q)", err, out)
+	}
+	if exitErr.ExitCode() != 5 || string(out) != "[a, b]\n" {
+		t.Errorf("script exited with %d and printed %q, want 5 and %q (stderr %q)",
+			exitErr.ExitCode(), out, "[a, b]\n", exitErr.Stderr)
+	}
+}
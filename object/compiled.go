@@ -0,0 +1,60 @@
+package object
+
+import (
+	"cathon/ast"
+	"cathon/code"
+	"fmt"
+)
+
+// CompiledFunction is a function literal compiled by package compiler. It
+// lives in the constant pool; scripts only ever see the Closures made of
+// it.
+type CompiledFunction struct {
+	Instructions  code.Instructions
+	NumLocals     int
+	NumParameters int
+
+	// Locals names the local slots, for the error when one is read before
+	// its let ran.
+	Locals []string
+	// Cells are the local slots that closures capture. The vm keeps them
+	// in cells the closures share, so they see later lets.
+	Cells []int
+	// Free lists where the variables a closure of the function captures
+	// come from, in the order of Closure.Free.
+	Free []FreeVariable
+
+	// Parameters and Body are the literal's, to print the function.
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+}
+
+// FreeVariable is a variable a function uses from the one around it: a
+// local slot of it if Local, otherwise one of its own free variables.
+type FreeVariable struct {
+	Name  string
+	Local bool
+	Index int
+}
+
+func (cf *CompiledFunction) Type() ObjectType         { return COMPILED_FUNCTION_OBJ }
+func (cf *CompiledFunction) Equals(other Object) bool { return cf == other }
+func (cf *CompiledFunction) Inspect() string {
+	return fmt.Sprintf("CompiledFunction[%p]", cf)
+}
+
+// Closure is a function of a compiled script. It has the type FUNCTION, as
+// the evaluator's Function does, so scripts behave the same either way.
+type Closure struct {
+	Fn *CompiledFunction
+	// Free holds the captured variables, in the cells of package vm.
+	Free []Object
+}
+
+func (c *Closure) Type() ObjectType { return FUNCTION_OBJ }
+
+// Closures are only equal to themselves.
+func (c *Closure) Equals(other Object) bool { return c == other }
+func (c *Closure) Inspect() string {
+	return inspectFunction(c.Fn.Parameters, c.Fn.Body)
+}
@@ -0,0 +1,277 @@
+package object
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"unicode"
+	"unicode/utf8"
+)
+
+// HostObject is a value owned by the embedding Go program. Scripts reach
+// it through dot access: cat.name, cat.x = 10 and cat.jump(3).
+type HostObject interface {
+	Object
+	GetField(name string) (Object, error)
+	SetField(name string, value Object) error
+	CallMethod(name string, args []Object) (Object, error)
+}
+
+// Host exposes a Go struct pointer as a HostObject. Exported fields and
+// methods are visible to scripts under their lower camel case names, and
+// every read and write goes to the live struct.
+type Host struct {
+	value reflect.Value
+}
+
+// NewHost wraps ptr, which must be a non-nil pointer to a struct.
+func NewHost(ptr interface{}) *Host {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("object.NewHost: want pointer to struct, got %T", ptr))
+	}
+	return &Host{value: v}
+}
+
+func (h *Host) Type() ObjectType { return HOST_OBJ }
+func (h *Host) Inspect() string {
+	return fmt.Sprintf("<%s>", h.value.Elem().Type().Name())
+}
+
+// Hosts are equal when they wrap the same Go value.
+func (h *Host) Equals(other Object) bool {
+	o, ok := other.(*Host)
+	return ok && h.value.Type() == o.value.Type() &&
+		h.value.Pointer() == o.value.Pointer()
+}
+
+// Value returns the wrapped pointer.
+func (h *Host) Value() interface{} { return h.value.Interface() }
+
+func (h *Host) GetField(name string) (Object, error) {
+	field := h.value.Elem().FieldByName(exportedName(name))
+	if !field.IsValid() || !isExported(exportedName(name)) {
+		if method := h.value.MethodByName(exportedName(name)); method.IsValid() {
+			return nil, fmt.Errorf("%s.%s is a method", h.Inspect(), name)
+		}
+		return nil, fmt.Errorf("%s has no field %s", h.Inspect(), name)
+	}
+	return ToObject(field.Interface())
+}
+
+func (h *Host) SetField(name string, value Object) error {
+	field := h.value.Elem().FieldByName(exportedName(name))
+	if !field.IsValid() || !isExported(exportedName(name)) {
+		return fmt.Errorf("%s has no field %s", h.Inspect(), name)
+	}
+
+	converted, err := fromObject(value, field.Type())
+	if err != nil {
+		return fmt.Errorf("cannot assign %s to %s.%s: %s",
+			value.Type(), h.Inspect(), name, err)
+	}
+	field.Set(converted)
+	return nil
+}
+
+func (h *Host) CallMethod(name string, args []Object) (Object, error) {
+	method := h.value.MethodByName(exportedName(name))
+	if !method.IsValid() {
+		return nil, fmt.Errorf("%s has no method %s", h.Inspect(), name)
+	}
+
+	methodType := method.Type()
+	if methodType.IsVariadic() || methodType.NumIn() != len(args) {
+		return nil, fmt.Errorf("wrong number of arguments to %s. got=%d, want=%d",
+			name, len(args), methodType.NumIn())
+	}
+
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		converted, err := fromObject(arg, methodType.In(i))
+		if err != nil {
+			return nil, fmt.Errorf("argument %d to %s: %s", i+1, name, err)
+		}
+		in[i] = converted
+	}
+
+	out := method.Call(in)
+
+	// A trailing error result is reported as a script error.
+	if n := len(out); n > 0 && methodType.Out(n-1) == errorType {
+		if err, _ := out[n-1].Interface().(error); err != nil {
+			return nil, err
+		}
+		out = out[:n-1]
+	}
+
+	switch len(out) {
+	case 0:
+		return NULL, nil
+	case 1:
+		return ToObject(out[0].Interface())
+	default:
+		elements := make([]Object, len(out))
+		for i, o := range out {
+			obj, err := ToObject(o.Interface())
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = obj
+		}
+		return &Array{Elements: elements}, nil
+	}
+}
+
+var (
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+	objectType = reflect.TypeOf((*Object)(nil)).Elem()
+)
+
+// ToObject converts a Go value into its script representation. Pointers to
+// structs become Hosts, so nested entities stay live as well.
+func ToObject(v interface{}) (Object, error) {
+	if v == nil {
+		return NULL, nil
+	}
+	if obj, ok := v.(Object); ok {
+		return obj, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Integer{Value: rv.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("value out of range: %d does not fit in INTEGER", rv.Uint())
+		}
+		return &Integer{Value: int64(rv.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Float{Value: rv.Float()}, nil
+	case reflect.Bool:
+		return nativeBoolToBooleanObject(rv.Bool()), nil
+	case reflect.String:
+		return &String{Value: rv.String()}, nil
+	case reflect.Slice, reflect.Array:
+		elements := make([]Object, rv.Len())
+		for i := range elements {
+			obj, err := ToObject(rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = obj
+		}
+		return &Array{Elements: elements}, nil
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return NULL, nil
+		}
+		if rv.Elem().Kind() == reflect.Struct {
+			return &Host{value: rv}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported host type %s", rv.Type())
+}
+
+func fromObject(obj Object, t reflect.Type) (reflect.Value, error) {
+	if t == objectType {
+		return reflect.ValueOf(&obj).Elem(), nil
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, ok := obj.(*Integer); ok {
+			if reflect.Zero(t).OverflowInt(i.Value) {
+				return reflect.Value{}, fmt.Errorf("value out of range: %d does not fit in %s", i.Value, t)
+			}
+			return reflect.ValueOf(i.Value).Convert(t), nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if i, ok := obj.(*Integer); ok {
+			if i.Value < 0 || reflect.Zero(t).OverflowUint(uint64(i.Value)) {
+				return reflect.Value{}, fmt.Errorf("value out of range: %d does not fit in %s", i.Value, t)
+			}
+			return reflect.ValueOf(i.Value).Convert(t), nil
+		}
+	case reflect.Float32, reflect.Float64:
+		switch n := obj.(type) {
+		case *Float:
+			return reflect.ValueOf(n.Value).Convert(t), nil
+		case *Integer:
+			return reflect.ValueOf(float64(n.Value)).Convert(t), nil
+		}
+	case reflect.Bool:
+		if b, ok := obj.(*Boolean); ok {
+			return reflect.ValueOf(b.Value).Convert(t), nil
+		}
+	case reflect.String:
+		if s, ok := obj.(*String); ok {
+			return reflect.ValueOf(s.Value).Convert(t), nil
+		}
+	case reflect.Slice:
+		if arr, ok := obj.(*Array); ok {
+			slice := reflect.MakeSlice(t, len(arr.Elements), len(arr.Elements))
+			for i, el := range arr.Elements {
+				converted, err := fromObject(el, t.Elem())
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				slice.Index(i).Set(converted)
+			}
+			return slice, nil
+		}
+	case reflect.Ptr:
+		if h, ok := obj.(*Host); ok && h.value.Type() == t {
+			return h.value, nil
+		}
+	}
+
+	return reflect.Value{}, fmt.Errorf("want %s, got %s", t, obj.Type())
+}
+
+// exportedName maps a script name such as "jump" to the Go name "Jump".
+func exportedName(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(r)) + name[size:]
+}
+
+func isExported(name string) bool {
+	r, _ := utf8.DecodeRuneInString(name)
+	return unicode.IsUpper(r)
+}
+
+// Module is a read-only namespace of builtins, such as string.split.
+type Module struct {
+	Name    string
+	Members map[string]Object
+}
+
+func (m *Module) Type() ObjectType         { return MODULE_OBJ }
+func (m *Module) Inspect() string          { return fmt.Sprintf("<module %s>", m.Name) }
+func (m *Module) Equals(other Object) bool { return m == other }
+
+func (m *Module) GetField(name string) (Object, error) {
+	member, ok := m.Members[name]
+	if !ok {
+		return nil, fmt.Errorf("module %s has no member %s", m.Name, name)
+	}
+	return member, nil
+}
+
+func (m *Module) SetField(name string, value Object) error {
+	return fmt.Errorf("cannot assign to module member %s.%s", m.Name, name)
+}
+
+func (m *Module) CallMethod(name string, args []Object) (Object, error) {
+	member, ok := m.Members[name]
+	if !ok {
+		return nil, fmt.Errorf("module %s has no member %s", m.Name, name)
+	}
+	builtin, ok := member.(*Builtin)
+	if !ok {
+		return nil, fmt.Errorf("%s.%s is not a function: %s", m.Name, name, member.Type())
+	}
+	return builtin.Fn(args...), nil
+}
@@ -3,8 +3,11 @@ package object
 import (
 	"bytes"
 	"cathon/ast"
+	"encoding/binary"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"strconv"
 	"strings"
 )
 
@@ -17,30 +20,80 @@ const (
 	ERROR_OBJ = "ERROR"
 
 	INTEGER_OBJ = "INTEGER"
+	FLOAT_OBJ   = "FLOAT"
 	BOOLEAN_OBJ = "BOOLEAN"
 	STRING_OBJ  = "STRING"
 
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
 
-	FUNCTION_OBJ = "FUNCTION"
-	BUILTIN_OBJ  = "BUILTIN"
+	FUNCTION_OBJ          = "FUNCTION"
+	BUILTIN_OBJ           = "BUILTIN"
+	COMPILED_FUNCTION_OBJ = "COMPILED_FUNCTION"
 
 	ARRAY_OBJ = "ARRAY"
 	HASH_OBJ  = "HASH"
+
+	HOST_OBJ   = "HOST"
+	MODULE_OBJ = "MODULE"
+)
+
+// The shared singletons; evaluators compare against them by identity.
+var (
+	NULL  = &Null{}
+	TRUE  = &Boolean{Value: true}
+	FALSE = &Boolean{Value: false}
 )
 
+func nativeBoolToBooleanObject(input bool) *Boolean {
+	if input {
+		return TRUE
+	}
+	return FALSE
+}
+
 type HashKey struct {
 	Type  ObjectType
 	Value uint64
 }
 
+// Hashable values can be hash keys. Different values may share a HashKey;
+// Hash confirms a match with Equals.
 type Hashable interface {
 	HashKey() HashKey
 }
 
+// IsHashable reports whether obj can be used as a hash key. Arrays can
+// when all of their elements can, unless they contain themselves.
+func IsHashable(obj Object) bool {
+	return isHashable(obj, make(map[*Array]bool))
+}
+
+// isHashable tracks the arrays it is inside in seen, so a cycle ends the
+// walk instead of overflowing the stack.
+func isHashable(obj Object, seen map[*Array]bool) bool {
+	if arr, ok := obj.(*Array); ok {
+		if seen[arr] {
+			return false
+		}
+		seen[arr] = true
+		defer delete(seen, arr)
+
+		for _, el := range arr.Elements {
+			if !isHashable(el, seen) {
+				return false
+			}
+		}
+		return true
+	}
+
+	_, ok := obj.(Hashable)
+	return ok
+}
+
 type Object interface {
 	Type() ObjectType
 	Inspect() string
+	Equals(other Object) bool
 }
 
 type Integer struct {
@@ -49,8 +102,58 @@ type Integer struct {
 
 func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
 func (i *Integer) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
+
+// Equals compares with floats as == does, by widening the integer.
+func (i *Integer) Equals(other Object) bool {
+	switch o := other.(type) {
+	case *Integer:
+		return i.Value == o.Value
+	case *Float:
+		return float64(i.Value) == o.Value
+	}
+	return false
+}
 func (i *Integer) HashKey() HashKey {
-	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+	return numberHashKey(float64(i.Value))
+}
+
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+
+// Inspect always shows a fraction or exponent so 2.0 doesn't read as
+// the integer 2.
+func (f *Float) Inspect() string {
+	str := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(str, ".eIN") {
+		str += ".0"
+	}
+	return str
+}
+func (f *Float) Equals(other Object) bool {
+	switch o := other.(type) {
+	case *Float:
+		return f.Value == o.Value
+	case *Integer:
+		return f.Value == float64(o.Value)
+	}
+	return false
+}
+func (f *Float) HashKey() HashKey {
+	return numberHashKey(f.Value)
+}
+
+// numberHashKey gives numbers that are == the same key: a whole number
+// hashes as an integer whatever its type, which also folds -0.0 into 0.
+// Integers are widened first, so those too large to be exact floats share
+// keys with their neighbours and Equals tells them apart.
+func numberHashKey(value float64) HashKey {
+	if value == math.Trunc(value) && value >= math.MinInt64 && value < math.MaxInt64 {
+		return HashKey{Type: INTEGER_OBJ, Value: uint64(int64(value))}
+	}
+	return HashKey{Type: FLOAT_OBJ, Value: math.Float64bits(value)}
 }
 
 type Boolean struct {
@@ -59,6 +162,10 @@ type Boolean struct {
 
 func (b *Boolean) Type() ObjectType { return BOOLEAN_OBJ }
 func (b *Boolean) Inspect() string  { return fmt.Sprintf("%t", b.Value) }
+func (b *Boolean) Equals(other Object) bool {
+	o, ok := other.(*Boolean)
+	return ok && b.Value == o.Value
+}
 func (b *Boolean) HashKey() HashKey {
 	var value uint64
 
@@ -75,6 +182,10 @@ type Null struct{}
 
 func (n *Null) Type() ObjectType { return NULL_OBJ }
 func (n *Null) Inspect() string  { return "null" }
+func (n *Null) Equals(other Object) bool {
+	_, ok := other.(*Null)
+	return ok
+}
 
 type ReturnValue struct {
 	Value Object
@@ -82,6 +193,10 @@ type ReturnValue struct {
 
 func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
+func (rv *ReturnValue) Equals(other Object) bool {
+	o, ok := other.(*ReturnValue)
+	return ok && Equal(rv.Value, o.Value)
+}
 
 type Error struct {
 	Message string
@@ -89,6 +204,10 @@ type Error struct {
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
 func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
+func (e *Error) Equals(other Object) bool {
+	o, ok := other.(*Error)
+	return ok && e.Message == o.Message
+}
 
 type Function struct {
 	Parameters []*ast.Identifier
@@ -97,11 +216,18 @@ type Function struct {
 }
 
 func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
+
+// Functions are only equal to themselves.
+func (f *Function) Equals(other Object) bool { return f == other }
 func (f *Function) Inspect() string {
+	return inspectFunction(f.Parameters, f.Body)
+}
+
+func inspectFunction(parameters []*ast.Identifier, body *ast.BlockStatement) string {
 	var out bytes.Buffer
 
 	params := []string{}
-	for _, p := range f.Parameters {
+	for _, p := range parameters {
 		params = append(params, p.String())
 	}
 
@@ -109,7 +235,7 @@ func (f *Function) Inspect() string {
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") {\n")
-	out.WriteString(f.Body.String())
+	out.WriteString(body.String())
 	out.WriteString("\n}")
 
 	return out.String()
@@ -121,6 +247,10 @@ type String struct {
 
 func (s *String) Type() ObjectType { return STRING_OBJ }
 func (s *String) Inspect() string  { return s.Value }
+func (s *String) Equals(other Object) bool {
+	o, ok := other.(*String)
+	return ok && s.Value == o.Value
+}
 func (s *String) HashKey() HashKey {
 	h := fnv.New64a()
 	h.Write([]byte(s.Value))
@@ -132,14 +262,16 @@ type Builtin struct {
 	Fn BuiltinFunction
 }
 
-func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
-func (b *Builtin) Inspect() string  { return "builtin function" }
+func (b *Builtin) Type() ObjectType         { return BUILTIN_OBJ }
+func (b *Builtin) Inspect() string          { return "builtin function" }
+func (b *Builtin) Equals(other Object) bool { return b == other }
 
 type Array struct {
 	Elements []Object
 }
 
-func (ao *Array) Type() ObjectType { return ARRAY_OBJ }
+func (ao *Array) Type() ObjectType         { return ARRAY_OBJ }
+func (ao *Array) Equals(other Object) bool { return Equal(ao, other) }
 func (ao *Array) Inspect() string {
 	var out bytes.Buffer
 
@@ -155,21 +287,114 @@ func (ao *Array) Inspect() string {
 	return out.String()
 }
 
+// HashKey hashes the elements' keys in order. Only arrays that pass
+// IsHashable should be used as keys; an unhashable element, or an array
+// that contains itself, contributes just its type.
+func (ao *Array) HashKey() HashKey {
+	return ao.hashKey(make(map[*Array]bool))
+}
+
+func (ao *Array) hashKey(seen map[*Array]bool) HashKey {
+	seen[ao] = true
+	defer delete(seen, ao)
+
+	h := fnv.New64a()
+	var buf [8]byte
+
+	for _, el := range ao.Elements {
+		h.Write([]byte(el.Type()))
+
+		var key HashKey
+		switch el := el.(type) {
+		case *Array:
+			if seen[el] {
+				continue
+			}
+			key = el.hashKey(seen)
+		case Hashable:
+			key = el.HashKey()
+		default:
+			continue
+		}
+		binary.LittleEndian.PutUint64(buf[:], key.Value)
+		h.Write(buf[:])
+	}
+
+	return HashKey{Type: ao.Type(), Value: h.Sum64()}
+}
+
 type HashPair struct {
 	Key   Object
 	Value Object
 }
 
+// Hash keeps its pairs in insertion order. Overwriting a key keeps the
+// position of its first insertion.
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	index map[HashKey][]int // positions in pairs of the keys with a HashKey
+	pairs []HashPair
+}
+
+func NewHash() *Hash {
+	return &Hash{index: make(map[HashKey][]int)}
 }
 
-func (h *Hash) Type() ObjectType { return HASH_OBJ }
+// Set stores value under key and reports whether key was hashable.
+func (h *Hash) Set(key, value Object) bool {
+	if !IsHashable(key) {
+		return false
+	}
+	if h.index == nil {
+		h.index = make(map[HashKey][]int)
+	}
+
+	hashKey := key.(Hashable).HashKey()
+	if i, ok := h.find(hashKey, key); ok {
+		h.pairs[i] = HashPair{Key: key, Value: value}
+		return true
+	}
+
+	h.index[hashKey] = append(h.index[hashKey], len(h.pairs))
+	h.pairs = append(h.pairs, HashPair{Key: key, Value: value})
+	return true
+}
+
+// Get looks up key. Unhashable keys are never present.
+func (h *Hash) Get(key Object) (Object, bool) {
+	if !IsHashable(key) {
+		return nil, false
+	}
+
+	i, ok := h.find(key.(Hashable).HashKey(), key)
+	if !ok {
+		return nil, false
+	}
+	return h.pairs[i].Value, true
+}
+
+// find confirms key against every pair whose key shares its HashKey.
+func (h *Hash) find(hashKey HashKey, key Object) (int, bool) {
+	for _, i := range h.index[hashKey] {
+		if h.pairs[i].Key.Equals(key) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func (h *Hash) Len() int { return len(h.pairs) }
+
+// Pairs returns the pairs in insertion order. The slice is shared with
+// the hash and must not be modified.
+func (h *Hash) Pairs() []HashPair { return h.pairs }
+
+func (h *Hash) Type() ObjectType         { return HASH_OBJ }
+func (h *Hash) Equals(other Object) bool { return Equal(h, other) }
 func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for _, pair := range h.Pairs {
+	for _, pair := range h.pairs {
 		pairs = append(pairs, fmt.Sprintf("%s: %s",
 			pair.Key.Inspect(), pair.Value.Inspect()))
 	}
@@ -180,3 +405,59 @@ func (h *Hash) Inspect() string {
 
 	return out.String()
 }
+
+// Equal reports whether a and b hold the same value. Arrays and hashes are
+// compared element by element. A pair of values that is already being
+// compared further up is assumed equal, so cyclic values terminate.
+func Equal(a, b Object) bool {
+	return equal(a, b, make(map[[2]Object]bool))
+}
+
+func equal(a, b Object, seen map[[2]Object]bool) bool {
+	if a == b {
+		return true
+	}
+	if a == nil || b == nil {
+		return false
+	}
+
+	switch a := a.(type) {
+	case *Array:
+		b, ok := b.(*Array)
+		if !ok || len(a.Elements) != len(b.Elements) {
+			return false
+		}
+		if seen[[2]Object{a, b}] {
+			return true
+		}
+		seen[[2]Object{a, b}] = true
+
+		for i := range a.Elements {
+			if !equal(a.Elements[i], b.Elements[i], seen) {
+				return false
+			}
+		}
+		return true
+
+	case *Hash:
+		b, ok := b.(*Hash)
+		if !ok || a.Len() != b.Len() {
+			return false
+		}
+		if seen[[2]Object{a, b}] {
+			return true
+		}
+		seen[[2]Object{a, b}] = true
+
+		for _, pair := range a.pairs {
+			other, ok := b.Get(pair.Key)
+			if !ok || !equal(pair.Value, other, seen) {
+				return false
+			}
+		}
+		return true
+
+	default:
+		return a.Equals(b)
+	}
+}
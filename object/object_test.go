@@ -1,6 +1,10 @@
 package object
 
-import "testing"
+import (
+	"fmt"
+	"math"
+	"testing"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -58,3 +62,331 @@ func TestIntegerHashKey(t *testing.T) {
 		t.Errorf("integers with twoerent content have same hash keys")
 	}
 }
+
+func TestNumberHashKey(t *testing.T) {
+	tests := []struct {
+		a, b Object
+	}{
+		{&Integer{Value: 1}, &Float{Value: 1}},
+		{&Integer{Value: -7}, &Float{Value: -7}},
+		{&Integer{Value: 0}, &Float{Value: math.Copysign(0, -1)}},
+		{&Float{Value: 0}, &Float{Value: math.Copysign(0, -1)}},
+		{&Integer{Value: 1 << 53}, &Float{Value: 1 << 53}},
+		{&Integer{Value: 1<<53 + 1}, &Float{Value: 1 << 53}},
+		{&Integer{Value: math.MaxInt64}, &Float{Value: math.MaxInt64}},
+	}
+
+	for _, tt := range tests {
+		if !tt.a.Equals(tt.b) {
+			t.Errorf("%s.Equals(%s) is false", tt.a.Inspect(), tt.b.Inspect())
+		}
+		if tt.a.(Hashable).HashKey() != tt.b.(Hashable).HashKey() {
+			t.Errorf("%s and %s have different hash keys", tt.a.Inspect(), tt.b.Inspect())
+		}
+	}
+
+	if (&Float{Value: 1.5}).HashKey() == (&Float{Value: 1}).HashKey() {
+		t.Errorf("1.5 has same hash key as 1.0")
+	}
+
+	h := NewHash()
+	h.Set(&Integer{Value: 1}, &String{Value: "a"})
+	if value, ok := h.Get(&Float{Value: 1}); !ok || value.Inspect() != "a" {
+		t.Errorf("hash lookup of 1.0 wrong. got=%v, %t", value, ok)
+	}
+}
+
+type testRoom struct {
+	Name  string
+	Cats  []string
+	Owner *testOwner
+}
+
+type testOwner struct {
+	Gold int
+}
+
+type testCounters struct {
+	Small  int8
+	Count  uint16
+	Large  uint64
+	Signed int32
+}
+
+func TestHostIntegerRange(t *testing.T) {
+	counters := &testCounters{Small: 1, Count: 2, Large: math.MaxUint64, Signed: 3}
+	host := NewHost(counters)
+
+	tests := []struct {
+		field string
+		value int64
+		err   string
+	}{
+		{"small", 127, ""},
+		{"small", -128, ""},
+		{"small", 300, "cannot assign INTEGER to <testCounters>.small: value out of range: 300 does not fit in int8"},
+		{"small", -129, "cannot assign INTEGER to <testCounters>.small: value out of range: -129 does not fit in int8"},
+		{"count", 65535, ""},
+		{"count", 65536, "cannot assign INTEGER to <testCounters>.count: value out of range: 65536 does not fit in uint16"},
+		{"count", -1, "cannot assign INTEGER to <testCounters>.count: value out of range: -1 does not fit in uint16"},
+		{"signed", math.MinInt32, ""},
+		{"signed", math.MaxInt32 + 1, "cannot assign INTEGER to <testCounters>.signed: value out of range: 2147483648 does not fit in int32"},
+	}
+
+	for _, tt := range tests {
+		err := host.SetField(tt.field, &Integer{Value: tt.value})
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("SetField(%s, %d) returned error: %s", tt.field, tt.value, err)
+				continue
+			}
+			if got, _ := host.GetField(tt.field); got.Inspect() != fmt.Sprint(tt.value) {
+				t.Errorf("%s has wrong value. expected=%d, got=%s", tt.field, tt.value, got.Inspect())
+			}
+			continue
+		}
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("SetField(%s, %d) wrong error. expected=%q, got=%v", tt.field, tt.value, tt.err, err)
+		}
+	}
+
+	if counters.Small != -128 || counters.Count != 65535 {
+		t.Errorf("out of range values were stored. got small=%d, count=%d", counters.Small, counters.Count)
+	}
+
+	_, err := host.GetField("large")
+	if err == nil || err.Error() != "value out of range: 18446744073709551615 does not fit in INTEGER" {
+		t.Errorf("GetField(large) wrong error. got=%v", err)
+	}
+}
+
+func TestHostFields(t *testing.T) {
+	room := &testRoom{Name: "attic", Cats: []string{"Nabi"}, Owner: &testOwner{Gold: 3}}
+	host := NewHost(room)
+
+	name, err := host.GetField("name")
+	if err != nil {
+		t.Fatalf("GetField returned error: %s", err)
+	}
+	if name.Inspect() != "attic" {
+		t.Errorf("name has wrong value. got=%q", name.Inspect())
+	}
+
+	cats, err := host.GetField("cats")
+	if err != nil {
+		t.Fatalf("GetField returned error: %s", err)
+	}
+	if cats.Inspect() != "[Nabi]" {
+		t.Errorf("cats has wrong value. got=%q", cats.Inspect())
+	}
+
+	owner, err := host.GetField("owner")
+	if err != nil {
+		t.Fatalf("GetField returned error: %s", err)
+	}
+	if err := owner.(HostObject).SetField("gold", &Integer{Value: 7}); err != nil {
+		t.Fatalf("SetField returned error: %s", err)
+	}
+	if room.Owner.Gold != 7 {
+		t.Errorf("nested host did not write through. got=%d", room.Owner.Gold)
+	}
+
+	if err := host.SetField("cats", &Array{Elements: []Object{&String{Value: "Yaong"}}}); err != nil {
+		t.Fatalf("SetField returned error: %s", err)
+	}
+	if len(room.Cats) != 1 || room.Cats[0] != "Yaong" {
+		t.Errorf("cats did not write through. got=%v", room.Cats)
+	}
+
+	if _, err := host.GetField("missing"); err == nil {
+		t.Errorf("expected error for missing field")
+	}
+}
+
+func TestEqual(t *testing.T) {
+	one := &Integer{Value: 1}
+	two := &Integer{Value: 2}
+	str := &String{Value: "one"}
+
+	cyclicA := &Array{}
+	cyclicA.Elements = []Object{one, cyclicA}
+	cyclicB := &Array{}
+	cyclicB.Elements = []Object{one, cyclicB}
+
+	hash := func(key, value Object) *Hash {
+		h := NewHash()
+		h.Set(key, value)
+		return h
+	}
+
+	tests := []struct {
+		a, b     Object
+		expected bool
+	}{
+		{one, &Integer{Value: 1}, true},
+		{one, two, false},
+		{str, &String{Value: "one"}, true},
+		{one, str, false},
+		{NULL, &Null{}, true},
+		{TRUE, &Boolean{Value: true}, true},
+		{&Array{Elements: []Object{one, str}}, &Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "one"}}}, true},
+		{&Array{Elements: []Object{one}}, &Array{Elements: []Object{two}}, false},
+		{&Array{Elements: []Object{one}}, &Array{Elements: []Object{one, one}}, false},
+		{hash(str, &Array{Elements: []Object{one}}), hash(str, &Array{Elements: []Object{one}}), true},
+		{hash(str, one), hash(str, two), false},
+		{hash(str, one), hash(one, one), false},
+		{cyclicA, cyclicB, true},
+		{&Function{}, &Function{}, false},
+		{one, &Float{Value: 1}, true},
+		{&Float{Value: 1}, one, true},
+		{&Float{Value: 1.5}, one, false},
+		{&Float{Value: math.Copysign(0, -1)}, &Float{Value: 0}, true},
+		{&Array{Elements: []Object{one}}, &Array{Elements: []Object{&Float{Value: 1}}}, true},
+	}
+
+	for i, tt := range tests {
+		if got := Equal(tt.a, tt.b); got != tt.expected {
+			t.Errorf("tests[%d] - Equal(%s, %s) wrong. want=%t, got=%t",
+				i, tt.a.Inspect(), tt.b.Inspect(), tt.expected, got)
+		}
+		if got := tt.a.Equals(tt.b); got != tt.expected {
+			t.Errorf("tests[%d] - %s.Equals(%s) wrong. want=%t, got=%t",
+				i, tt.a.Inspect(), tt.b.Inspect(), tt.expected, got)
+		}
+	}
+}
+
+func TestHashOrder(t *testing.T) {
+	h := NewHash()
+	for _, key := range []string{"zebra", "apple", "mango", "kiwi"} {
+		h.Set(&String{Value: key}, &Integer{Value: int64(len(key))})
+	}
+	h.Set(&String{Value: "apple"}, &Integer{Value: 0})
+
+	if h.Len() != 4 {
+		t.Fatalf("hash has wrong length. got=%d", h.Len())
+	}
+
+	expected := "{zebra: 5, apple: 0, mango: 5, kiwi: 4}"
+	if h.Inspect() != expected {
+		t.Errorf("hash.Inspect() wrong. want=%q, got=%q", expected, h.Inspect())
+	}
+
+	value, ok := h.Get(&String{Value: "mango"})
+	if !ok || value.Inspect() != "5" {
+		t.Errorf("hash.Get(mango) wrong. got=%v, %t", value, ok)
+	}
+
+	if _, ok := h.Get(&Builtin{}); ok {
+		t.Errorf("hash.Get found an unhashable key")
+	}
+	if h.Set(&Builtin{}, NULL) {
+		t.Errorf("hash.Set accepted an unhashable key")
+	}
+}
+
+// collidingKey hashes every value to the same HashKey.
+type collidingKey struct{ String }
+
+func (c *collidingKey) HashKey() HashKey { return HashKey{Type: STRING_OBJ, Value: 42} }
+func (c *collidingKey) Equals(other Object) bool {
+	o, ok := other.(*collidingKey)
+	return ok && c.Value == o.Value
+}
+
+func TestHashCollisions(t *testing.T) {
+	a := &collidingKey{String{Value: "a"}}
+	b := &collidingKey{String{Value: "b"}}
+	if a.HashKey() != b.HashKey() {
+		t.Fatalf("test keys do not collide")
+	}
+
+	h := NewHash()
+	h.Set(a, &Integer{Value: 1})
+	h.Set(b, &Integer{Value: 2})
+	h.Set(&collidingKey{String{Value: "a"}}, &Integer{Value: 3})
+
+	if h.Len() != 2 {
+		t.Fatalf("hash has wrong length. got=%d", h.Len())
+	}
+
+	tests := []struct {
+		key      Object
+		expected string
+	}{
+		{a, "3"},
+		{b, "2"},
+	}
+	for _, tt := range tests {
+		value, ok := h.Get(tt.key)
+		if !ok {
+			t.Errorf("no pair for key %s", tt.key.Inspect())
+			continue
+		}
+		if value.Inspect() != tt.expected {
+			t.Errorf("wrong value for key %s. want=%s, got=%s",
+				tt.key.Inspect(), tt.expected, value.Inspect())
+		}
+	}
+
+	if _, ok := h.Get(&collidingKey{String{Value: "c"}}); ok {
+		t.Errorf("hash found a key that was never set")
+	}
+}
+
+func TestArrayHashKey(t *testing.T) {
+	one := &Integer{Value: 1}
+	arr1 := &Array{Elements: []Object{one, &String{Value: "a"}}}
+	arr2 := &Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "a"}}}
+	diff := &Array{Elements: []Object{&String{Value: "a"}, one}}
+	nested := &Array{Elements: []Object{arr1}}
+
+	if arr1.HashKey() != arr2.HashKey() {
+		t.Errorf("arrays with same content have different hash keys")
+	}
+	if arr1.HashKey() == diff.HashKey() {
+		t.Errorf("arrays with different content have same hash keys")
+	}
+	if !IsHashable(nested) {
+		t.Errorf("nested array of hashable values is not hashable")
+	}
+	if IsHashable(&Array{Elements: []Object{one, &Builtin{}}}) {
+		t.Errorf("array containing a builtin is hashable")
+	}
+
+	cyclic := &Array{Elements: []Object{one}}
+	cyclic.Elements = append(cyclic.Elements, &Array{Elements: []Object{cyclic}})
+	if IsHashable(cyclic) {
+		t.Errorf("array containing itself is hashable")
+	}
+	cyclic.HashKey()
+
+	shared := &Array{Elements: []Object{arr1, arr1}}
+	if !IsHashable(shared) {
+		t.Errorf("array holding the same array twice is not hashable")
+	}
+	if NewHash().Set(cyclic, one) {
+		t.Errorf("array containing itself was used as a key")
+	}
+}
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{2, "2.0"},
+		{-0.5, "-0.5"},
+		{1e21, "1e+21"},
+		{math.Inf(1), "+Inf"},
+		{math.NaN(), "NaN"},
+	}
+
+	for _, tt := range tests {
+		f := &Float{Value: tt.value}
+		if f.Inspect() != tt.expected {
+			t.Errorf("wrong Inspect for %v. got=%q, want=%q",
+				tt.value, f.Inspect(), tt.expected)
+		}
+	}
+}
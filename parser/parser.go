@@ -10,8 +10,9 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	ASSIGN      // x.y = z
 	EQUALS      //==
-	LESSGREATER // <, >
+	LESSGREATER // <, >, <=, >=, in
 	SUM         //+
 	PRODUCT     //*
 	PREFIX      //-x, !x
@@ -21,9 +22,10 @@ const (
 type Parser struct {
 	l *lexer.Lexer
 
-	errors    []string
-	curToken  token.Token
-	peekToken token.Token
+	errors      []string
+	errorTokens []token.Token
+	curToken    token.Token
+	peekToken   token.Token
 
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
@@ -37,12 +39,17 @@ var precedences = map[token.TokenType]int{
 	token.NOTEQ:    EQUALS,
 	token.LT:       LESSGREATER,
 	token.GT:       LESSGREATER,
+	token.LTE:      LESSGREATER,
+	token.GTE:      LESSGREATER,
+	token.IN:       LESSGREATER,
 	token.PLUS:     SUM,
 	token.MINUS:    SUM,
 	token.SLASH:    PRODUCT,
 	token.ASTERISK: PRODUCT,
 	token.LPAREN: CALL,
 	token.LBRACKET: INDEX,
+	token.DOT:      INDEX,
+	token.ASSIGN:   ASSIGN,
 }
 
 func New(lexerP *lexer.Lexer) *Parser {
@@ -56,6 +63,7 @@ func New(lexerP *lexer.Lexer) *Parser {
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.ParseIdentifier)
 	p.registerPrefix(token.INT, p.ParseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.ParseFloatLiteral)
 	p.registerPrefix(token.STRING, p.ParseStringLiteral)
 	p.registerPrefix(token.BANG, p.ParsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.ParsePrefixExpression)
@@ -75,8 +83,13 @@ func New(lexerP *lexer.Lexer) *Parser {
 	p.registerInfix(token.NOTEQ, p.ParseInfixExpression)
 	p.registerInfix(token.LT, p.ParseInfixExpression)
 	p.registerInfix(token.GT, p.ParseInfixExpression)
+	p.registerInfix(token.LTE, p.ParseInfixExpression)
+	p.registerInfix(token.GTE, p.ParseInfixExpression)
+	p.registerInfix(token.IN, p.ParseInfixExpression)
 	p.registerInfix(token.LPAREN, p.ParseCallExpression)
 	p.registerInfix(token.LBRACKET, p.ParseIndexExpression)
+	p.registerInfix(token.DOT, p.ParseDotExpression)
+	p.registerInfix(token.ASSIGN, p.ParseAssignExpression)
 
 	return p
 }
@@ -84,7 +97,6 @@ func (p *Parser) NextToken() {
 	defer untrace(trace("NextToken"))
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
-	fmt.Printf("%s %p\n", p.curToken.Literal, p)
 }
 func (parserP *Parser) ParseIdentifier() ast.Expression {
 	defer untrace(trace("ParseIdentifier"))
@@ -97,7 +109,19 @@ func (parserP *Parser) ParseIntegerLiteral() ast.Expression {
 	value, err := strconv.ParseInt(parserP.curToken.Literal, 0, 64)
 	if err != nil {
 		msg := fmt.Sprintf("could not parse %q as integer", parserP.curToken.Literal)
-		parserP.errors = append(parserP.errors, msg)
+		parserP.addError(parserP.curToken, msg)
+		return nil
+	}
+	lit.Value = value
+	return lit
+}
+func (parserP *Parser) ParseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: parserP.curToken}
+
+	value, err := strconv.ParseFloat(parserP.curToken.Literal, 64)
+	if err != nil {
+		msg := fmt.Sprintf("could not parse %q as float", parserP.curToken.Literal)
+		parserP.addError(parserP.curToken, msg)
 		return nil
 	}
 	lit.Value = value
@@ -108,7 +132,6 @@ func (p *Parser) ParseStringLiteral() ast.Expression {
 }
 func (parserP *Parser) ParseBool() ast.Expression {
 	defer untrace(trace("ParseBool: " + parserP.curToken.Literal))
-	fmt.Printf("%p\n", parserP)
 	return &ast.Boolean{Token: parserP.curToken, Value: parserP.CurTokenIs(token.TRUE)}
 }
 func (parserP *Parser) ParseGroupedExpression() ast.Expression {
@@ -203,6 +226,7 @@ func (parserP *Parser) ParseBlockStatement() *ast.BlockStatement {
 		}
 		parserP.NextToken()
 	}
+	block.End = parserP.curToken
 	return block
 }
 func (parserP *Parser) ParsePrefixExpression() ast.Expression {
@@ -265,7 +289,11 @@ func (parserP *Parser) ParseStatement() ast.Statement {
 	defer untrace(trace("ParseStatement"))
 	switch parserP.curToken.Type {
 	case token.LET:
-		return parserP.ParseLetStatement()
+		// A nil *ast.LetStatement would be a non-nil ast.Statement.
+		if stmt := parserP.ParseLetStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
 	case token.RETURN:
 		return parserP.ParseReturnStatement()
 	default:
@@ -287,7 +315,7 @@ func (parserP *Parser) ParseLetStatement() *ast.LetStatement {
 	}
 	parserP.NextToken()
 	stmt.Value = parserP.ParseExpression(LOWEST)
-	for !parserP.CurTokenIs(token.SEMICOLON) {
+	if parserP.PeekTokenIs(token.SEMICOLON) {
 		parserP.NextToken()
 	}
 
@@ -301,7 +329,7 @@ func (parserP *Parser) ParseReturnStatement() *ast.ReturnStatement {
 
 	stmt.ReturnValue = parserP.ParseExpression(LOWEST)
 
-	for !parserP.CurTokenIs(token.SEMICOLON) {
+	if parserP.PeekTokenIs(token.SEMICOLON) {
 		parserP.NextToken()
 	}
 
@@ -353,17 +381,25 @@ func (parserP *Parser) ExpectPeek(tokenType token.TokenType) bool {
 	}
 }
 func (parserP *Parser) PeekError(tokenType token.TokenType) {
-		fmt.Printf("wth its not working")
 	msg := fmt.Sprintf("expected next token to be %s, got %s instead", tokenType, parserP.peekToken.Type)
+	parserP.addError(parserP.peekToken, msg)
+}
+func (parserP *Parser) addError(tok token.Token, msg string) {
 	parserP.errors = append(parserP.errors, msg)
+	parserP.errorTokens = append(parserP.errorTokens, tok)
 }
 func (parserP *Parser) Errors() []string {
 	return parserP.errors
 }
+
+// ErrorTokens returns the token each of Errors was found at, in the same
+// order, so editors can point at it.
+func (parserP *Parser) ErrorTokens() []token.Token {
+	return parserP.errorTokens
+}
 func (parserP *Parser) RegisterParsePrefixError(tokenType token.TokenType) {
-	fmt.Printf("wth its not working")
 	msg := fmt.Sprintf("no parse prefix function for %s", tokenType)
-	parserP.errors = append(parserP.errors, msg)
+	parserP.addError(parserP.curToken, msg)
 }
 func (parserP *Parser) ParseCallExpression(function ast.Expression) ast.Expression {
 	defer untrace(trace("ParseCallExpression"))
@@ -431,10 +467,42 @@ func (parserP *Parser) ParseArrayLiteral() ast.Expression {
 }
 
 func (parserP *Parser) ParseIndexExpression(left ast.Expression) ast.Expression {
-	exp := &ast.IndexExpression{Token: parserP.curToken, Left: left}
+	tok := parserP.curToken
+
+	var index ast.Expression
+	if !parserP.PeekTokenIs(token.COLON) {
+		parserP.NextToken()
+		index = parserP.ParseExpression(LOWEST)
+
+		if !parserP.PeekTokenIs(token.COLON) {
+			if !parserP.ExpectPeek(token.RBRACKET) {
+				return nil
+			}
+			return &ast.IndexExpression{Token: tok, Left: left, Index: index}
+		}
+	}
 
 	parserP.NextToken()
-	exp.Index = parserP.ParseExpression(LOWEST)
+	return parserP.ParseSliceExpression(tok, left, index)
+}
+
+// ParseSliceExpression continues a[start:end:step] from its first colon;
+// every part is optional.
+func (parserP *Parser) ParseSliceExpression(tok token.Token, left, start ast.Expression) ast.Expression {
+	exp := &ast.SliceExpression{Token: tok, Left: left, Start: start}
+
+	if !parserP.PeekTokenIs(token.COLON) && !parserP.PeekTokenIs(token.RBRACKET) {
+		parserP.NextToken()
+		exp.End = parserP.ParseExpression(LOWEST)
+	}
+
+	if parserP.PeekTokenIs(token.COLON) {
+		parserP.NextToken()
+		if !parserP.PeekTokenIs(token.RBRACKET) {
+			parserP.NextToken()
+			exp.Step = parserP.ParseExpression(LOWEST)
+		}
+	}
 
 	if !parserP.ExpectPeek(token.RBRACKET) {
 		return nil
@@ -459,6 +527,7 @@ func (parserP *Parser) ParseHashLiteral() ast.Expression {
 		value := parserP.ParseExpression(LOWEST)
 
 		hash.Pairs[key] = value
+		hash.Keys = append(hash.Keys, key)
 
 		if !parserP.PeekTokenIs(token.RBRACE) && !parserP.ExpectPeek(token.COMMA) {
 			return nil
@@ -470,4 +539,31 @@ func (parserP *Parser) ParseHashLiteral() ast.Expression {
 	}
 
 	return hash
-}
\ No newline at end of file
+}
+
+func (parserP *Parser) ParseDotExpression(left ast.Expression) ast.Expression {
+	exp := &ast.DotExpression{Token: parserP.curToken, Left: left}
+
+	if !parserP.ExpectPeek(token.IDENT) {
+		return nil
+	}
+	exp.Property = &ast.Identifier{Token: parserP.curToken, Value: parserP.curToken.Literal}
+
+	return exp
+}
+
+func (parserP *Parser) ParseAssignExpression(target ast.Expression) ast.Expression {
+	exp := &ast.AssignExpression{Token: parserP.curToken, Target: target}
+
+	if _, ok := target.(*ast.DotExpression); !ok {
+		msg := fmt.Sprintf("invalid assignment target: %s", target)
+		parserP.addError(parserP.curToken, msg)
+		return nil
+	}
+
+	parserP.NextToken()
+	// assignment is right-associative: a.b = c.d = 1
+	exp.Value = parserP.ParseExpression(ASSIGN - 1)
+
+	return exp
+}
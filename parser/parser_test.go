@@ -3,6 +3,7 @@ package parser
 import (
 	"cathon/ast"
 	"cathon/lexer"
+	"cathon/token"
 	"fmt"
 	"strconv"
 	"strings"
@@ -27,6 +28,11 @@ func TestReturnStatements(t *testing.T) {
 	expectedIdentifiers := []string{"5", "true", "x"}
 	CheckParseStatements(t, input, 3, expectedIdentifiers, CheckReturnStatement)
 }
+func TestStatementsWithoutSemicolons(t *testing.T) {
+	CheckParseStatements(t, "let x = 5", 1, []string{"x,5"}, CheckLetStatement)
+	CheckParseStatements(t, "return x", 1, []string{"x"}, CheckReturnStatement)
+	CheckParseStatements(t, "let a = 1\nlet b = a", 2, []string{"a,1", "b,a"}, CheckLetStatement)
+}
 func CheckParseStatements(t *testing.T, input string, expectedStmtCount int, expectedIdentifiers []string, checkFunc func(*testing.T, ast.Statement, string)) {
 	testLexer := lexer.New(input)
 	testParser := New(testLexer)
@@ -154,6 +160,9 @@ func TestInfixExpressions(t *testing.T) {
 		{"105 > 6", 105, ">", 6},
 		{"106 == 7", 106, "==", 7},
 		{"107 != 8", 107, "!=", 8},
+		{"108 <= 9", 108, "<=", 9},
+		{"109 >= 10", 109, ">=", 10},
+		{"110 in 11", 110, "in", 11},
 	}
 	for i, tt := range infixTests {
 		testLexer := lexer.New(tt.input)
@@ -747,3 +756,158 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 		testFunc(value)
 	}
 }
+
+func TestParsingDotExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"cat.name", "(cat.name)"},
+		{"cat.pos.x", "((cat.pos).x)"},
+		{"cat.jump(3)", "(cat.jump)(3)"},
+		{"cat.x + 1", "((cat.x) + 1)"},
+		{"cats[0].name", "((cats[0]).name)"},
+		{"cat.x = 10", "((cat.x) = 10)"},
+		{"cat.x = dog.x = 1 + 2", "((cat.x) = ((dog.x) = (1 + 2)))"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		CheckParserErrors(t, p)
+
+		actual := program.String()
+		if actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestParsingInvalidAssignment(t *testing.T) {
+	l := lexer.New("1 + 2 = 3")
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) == 0 {
+		t.Fatalf("expected parser errors, got none")
+	}
+	if errors[0] != "invalid assignment target: (1 + 2)" {
+		t.Errorf("wrong error message. got=%q", errors[0])
+	}
+}
+
+func TestParsingSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a[1:3]", "(a[1:3])"},
+		{"a[:2]", "(a[:2])"},
+		{"a[-2:]", "(a[(-2):])"},
+		{"a[:]", "(a[:])"},
+		{"a[::2]", "(a[::2])"},
+		{"a[1::-1]", "(a[1::(-1)])"},
+		{"a[1:len(a) - 1:2]", "(a[1:(len(a) - 1):2])"},
+		{"a[1:][0]", "((a[1:])[0])"},
+		{"a[1]", "(a[1])"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		CheckParserErrors(t, p)
+
+		actual := program.String()
+		if actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestParsingHashLiteralKeyOrder(t *testing.T) {
+	input := `{"z": 1, 2: 2, "a": 3, true: 4}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	CheckParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+	}
+
+	expected := "{z:1, 2:2, a:3, true:4}"
+	if hash.String() != expected {
+		t.Errorf("hash.String() wrong. want=%q, got=%q", expected, hash.String())
+	}
+	if len(hash.Keys) != len(hash.Pairs) {
+		t.Errorf("hash.Keys has wrong length. got=%d", len(hash.Keys))
+	}
+}
+
+func TestFloatLiteralExpression(t *testing.T) {
+	input := "1.5; 0.25; 10.0"
+	expected := []float64{1.5, 0.25, 10}
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	CheckParserErrors(t, p)
+
+	if len(program.Statements) != len(expected) {
+		t.Fatalf("program.Statements does not contain %d Statements. got %d Statements",
+			len(expected), len(program.Statements))
+	}
+
+	for i, want := range expected {
+		stmt := program.Statements[i].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != want {
+			t.Errorf("literal.Value not %g. got=%g", want, literal.Value)
+		}
+	}
+}
+
+func TestErrorTokens(t *testing.T) {
+	l := lexer.New("let x = 1;\nlet = 2;\n1 + 2 = 3")
+	p := New(l)
+	program := p.ParseProgram()
+
+	for _, stmt := range program.Statements {
+		if let, ok := stmt.(*ast.LetStatement); ok && let == nil {
+			t.Errorf("program has a nil *ast.LetStatement")
+		}
+	}
+
+	errors, tokens := p.Errors(), p.ErrorTokens()
+	if len(errors) != len(tokens) {
+		t.Fatalf("got %d errors but %d error tokens", len(errors), len(tokens))
+	}
+	if tokens[0].Line != 2 || tokens[0].Column != 5 {
+		t.Errorf("wrong position for %q. got=%d:%d", errors[0], tokens[0].Line, tokens[0].Column)
+	}
+	last := tokens[len(tokens)-1]
+	if last.Line != 3 || last.Column != 7 {
+		t.Errorf("wrong position for %q. got=%d:%d", errors[len(errors)-1], last.Line, last.Column)
+	}
+}
+
+func TestBlockStatementEnd(t *testing.T) {
+	l := lexer.New("fn(x) {\n  x\n}")
+	p := New(l)
+	program := p.ParseProgram()
+	CheckParserErrors(t, p)
+
+	fn := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if fn.Body.End.Type != token.RBRACE || fn.Body.End.Line != 3 || fn.Body.End.Column != 1 {
+		t.Errorf("wrong block end. got=%+v", fn.Body.End)
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"strings"
 )
 
+// Trace makes the parser print the functions it enters and leaves.
+var Trace = false
+
 var traceLevel int = 0
 
 const traceIdentPlaceholder string = "\t"
@@ -20,13 +23,21 @@ func tracePrint(fs string) {
 func incIdent() { traceLevel = traceLevel + 1 }
 func decIdent() { traceLevel = traceLevel - 1 }
 
+// trace and untrace do nothing unless Trace is set, so that parsers can
+// run concurrently.
 func trace(msg string) string {
+	if !Trace {
+		return msg
+	}
 	incIdent()
 	tracePrint("BEGIN " + msg)
 	return msg
 }
 
 func untrace(msg string) {
+	if !Trace {
+		return
+	}
 	tracePrint("END " + msg)
 	decIdent()
 }
@@ -0,0 +1,227 @@
+// Package pretty formats values for people to read, as the REPL shows its
+// results. Unlike Inspect, it breaks nested arrays and hashes over indented
+// lines when they don't fit, shortens long collections and function
+// bodies, marks cycles, and can color values by type.
+package pretty
+
+import (
+	"cathon/ast"
+	"cathon/object"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Options control the layout. Zero values get the defaults noted.
+type Options struct {
+	// Color turns on ANSI colors.
+	Color bool
+	// Width is the line length that collections are broken to fit. 80.
+	Width int
+	// MaxItems is how many elements or pairs of a collection are shown
+	// before the rest are counted. 50.
+	MaxItems int
+	// Indent is added for each level of nesting. Two spaces.
+	Indent string
+}
+
+// ANSI color codes by kind of value.
+const (
+	colorNumber = "36"
+	colorString = "32"
+	colorBool   = "33"
+	colorNull   = "90"
+	colorError  = "31"
+	colorFunc   = "35"
+	colorOpaque = "34"
+	colorMarker = "90"
+)
+
+// Sprint formats obj.
+func Sprint(obj object.Object, opts Options) string {
+	if opts.Width <= 0 {
+		opts.Width = 80
+	}
+	if opts.MaxItems <= 0 {
+		opts.MaxItems = 50
+	}
+	if opts.Indent == "" {
+		opts.Indent = "  "
+	}
+
+	b := &builder{opts: opts, active: make(map[object.Object]bool)}
+	d := b.build(obj)
+
+	var out strings.Builder
+	b.render(&out, d, "")
+	return out.String()
+}
+
+// doc is the layout of a value before it is known whether it fits on a
+// line.
+type doc struct {
+	text  string
+	color string
+
+	// A collection has open and close set and its elements in items.
+	open, close string
+	items       []*doc
+
+	// key is set on the values of hash pairs.
+	key *doc
+}
+
+func (d *doc) isCollection() bool { return d.open != "" }
+
+// width is the length of d on one line.
+func (d *doc) width() int {
+	n := 0
+	if d.key != nil {
+		n += d.key.width() + len(": ")
+	}
+	if !d.isCollection() {
+		return n + utf8.RuneCountInString(d.text)
+	}
+
+	n += len(d.open) + len(d.close)
+	for i, item := range d.items {
+		if i > 0 {
+			n += len(", ")
+		}
+		n += item.width()
+	}
+	return n
+}
+
+type builder struct {
+	opts Options
+	// active holds the collections being built further up, to mark cycles.
+	active map[object.Object]bool
+}
+
+func (b *builder) build(obj object.Object) *doc {
+	switch obj := obj.(type) {
+	case *object.Integer, *object.Float:
+		return &doc{text: obj.Inspect(), color: colorNumber}
+	case *object.String:
+		return &doc{text: strconv.Quote(obj.Value), color: colorString}
+	case *object.Boolean:
+		return &doc{text: obj.Inspect(), color: colorBool}
+	case *object.Null:
+		return &doc{text: obj.Inspect(), color: colorNull}
+	case *object.Error:
+		return &doc{text: obj.Inspect(), color: colorError}
+	case *object.ReturnValue:
+		return b.build(obj.Value)
+	case *object.Function:
+		return b.function(obj.Parameters)
+	case *object.Closure:
+		return b.function(obj.Fn.Parameters)
+	case *object.Builtin:
+		return &doc{text: obj.Inspect(), color: colorFunc}
+
+	case *object.Array:
+		if b.active[obj] {
+			return &doc{text: "[...]", color: colorMarker}
+		}
+		b.active[obj] = true
+		defer delete(b.active, obj)
+
+		d := &doc{open: "[", close: "]"}
+		for i, el := range obj.Elements {
+			if i == b.opts.MaxItems {
+				d.items = append(d.items, b.more(len(obj.Elements)-i))
+				break
+			}
+			d.items = append(d.items, b.build(el))
+		}
+		return d
+
+	case *object.Hash:
+		if b.active[obj] {
+			return &doc{text: "{...}", color: colorMarker}
+		}
+		b.active[obj] = true
+		defer delete(b.active, obj)
+
+		d := &doc{open: "{", close: "}"}
+		for i, pair := range obj.Pairs() {
+			if i == b.opts.MaxItems {
+				d.items = append(d.items, b.more(obj.Len()-i))
+				break
+			}
+			value := b.build(pair.Value)
+			value.key = b.build(pair.Key)
+			d.items = append(d.items, value)
+		}
+		return d
+
+	default:
+		return &doc{text: obj.Inspect(), color: colorOpaque}
+	}
+}
+
+func (b *builder) function(parameters []*ast.Identifier) *doc {
+	params := make([]string, len(parameters))
+	for i, p := range parameters {
+		params[i] = p.String()
+	}
+	return &doc{text: "fn(" + strings.Join(params, ", ") + ") {...}", color: colorFunc}
+}
+
+func (b *builder) more(n int) *doc {
+	return &doc{text: fmt.Sprintf("... %d more", n), color: colorMarker}
+}
+
+// render writes d, breaking collections that don't fit in the width left
+// after indent onto one line per item.
+func (b *builder) render(out *strings.Builder, d *doc, indent string) {
+	if !d.isCollection() || len(d.items) == 0 || len(indent)+d.width() <= b.opts.Width {
+		b.renderFlat(out, d)
+		return
+	}
+
+	if d.key != nil {
+		b.renderFlat(out, d.key)
+		out.WriteString(": ")
+	}
+	inner := indent + b.opts.Indent
+	out.WriteString(d.open)
+	for i, item := range d.items {
+		if i > 0 {
+			out.WriteString(",")
+		}
+		out.WriteString("\n" + inner)
+		b.render(out, item, inner)
+	}
+	out.WriteString("\n" + indent + d.close)
+}
+
+func (b *builder) renderFlat(out *strings.Builder, d *doc) {
+	if d.key != nil {
+		b.renderFlat(out, d.key)
+		out.WriteString(": ")
+	}
+	if !d.isCollection() {
+		b.write(out, d)
+		return
+	}
+
+	out.WriteString(d.open)
+	for i, item := range d.items {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		b.renderFlat(out, item)
+	}
+	out.WriteString(d.close)
+}
+
+func (b *builder) write(out *strings.Builder, d *doc) {
+	if !b.opts.Color || d.color == "" {
+		out.WriteString(d.text)
+		return
+	}
+	out.WriteString("\x1b[" + d.color + "m" + d.text + "\x1b[0m")
+}
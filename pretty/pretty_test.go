@@ -0,0 +1,105 @@
+package pretty
+
+import (
+	"cathon/ast"
+	"cathon/object"
+	"cathon/token"
+	"strings"
+	"testing"
+)
+
+func TestSprint(t *testing.T) {
+	ident := func(name string) *ast.Identifier {
+		return &ast.Identifier{Token: token.Token{Type: token.IDENT, Literal: name}, Value: name}
+	}
+
+	tests := []struct {
+		value    object.Object
+		opts     Options
+		expected string
+	}{
+		{integer(5), Options{}, "5"},
+		{&object.Float{Value: 2}, Options{}, "2.0"},
+		{str("cat"), Options{}, `"cat"`},
+		{object.TRUE, Options{}, "true"},
+		{object.NULL, Options{}, "null"},
+		{&object.Error{Message: "oops"}, Options{}, "ERROR: oops"},
+		{&object.Function{Parameters: []*ast.Identifier{ident("x"), ident("y")}},
+			Options{}, "fn(x, y) {...}"},
+		{array(integer(1), str("a"), array()), Options{}, `[1, "a", []]`},
+		{hash(str("name"), str("Nabi"), integer(1), object.FALSE), Options{},
+			`{"name": "Nabi", 1: false}`},
+		{array(array(integer(1), integer(2)), hash(str("key"), str("value"))), Options{Width: 20},
+			"[\n  [1, 2],\n  {\"key\": \"value\"}\n]"},
+		{hash(str("pos"), array(integer(100), integer(200)), str("tags"), array(str("a"))),
+			Options{Width: 18, Indent: "    "},
+			"{\n    \"pos\": [\n        100,\n        200\n    ],\n    \"tags\": [\"a\"]\n}"},
+		{array(integer(1), integer(2), integer(3), integer(4)), Options{MaxItems: 2},
+			"[1, 2, ... 2 more]"},
+		{hash(integer(1), integer(1), integer(2), integer(2)), Options{MaxItems: 1},
+			"{1: 1, ... 1 more}"},
+		{array(integer(1), str("a"), object.NULL), Options{Color: true},
+			"[\x1b[36m1\x1b[0m, \x1b[32m\"a\"\x1b[0m, \x1b[90mnull\x1b[0m]"},
+	}
+
+	for _, tt := range tests {
+		got := Sprint(tt.value, tt.opts)
+		if got != tt.expected {
+			t.Errorf("wrong output for %s. expected=\n%s\ngot=\n%s",
+				tt.value.Inspect(), tt.expected, got)
+		}
+	}
+}
+
+func TestSprintCycles(t *testing.T) {
+	arr := array(integer(1))
+	arr.Elements = append(arr.Elements, arr)
+	h := object.NewHash()
+	h.Set(str("self"), h)
+	h.Set(str("list"), arr)
+
+	tests := []struct {
+		value    object.Object
+		expected string
+	}{
+		{arr, "[1, [...]]"},
+		{h, `{"self": {...}, "list": [1, [...]]}`},
+		// The same array twice isn't a cycle.
+		{array(array(integer(7)), array(integer(7))), "[[7], [7]]"},
+	}
+
+	for _, tt := range tests {
+		if got := Sprint(tt.value, Options{}); got != tt.expected {
+			t.Errorf("wrong output. expected=%q, got=%q", tt.expected, got)
+		}
+	}
+}
+
+func TestSprintWidth(t *testing.T) {
+	var elements []object.Object
+	for i := 0; i < 30; i++ {
+		elements = append(elements, integer(int64(i)))
+	}
+
+	out := Sprint(array(elements...), Options{})
+	for _, line := range strings.Split(out, "\n") {
+		if len(line) > 80 {
+			t.Errorf("line longer than the width: %q", line)
+		}
+	}
+}
+
+func integer(i int64) *object.Integer { return &object.Integer{Value: i} }
+func str(s string) *object.String     { return &object.String{Value: s} }
+
+func array(elements ...object.Object) *object.Array {
+	return &object.Array{Elements: elements}
+}
+
+func hash(kv ...object.Object) *object.Hash {
+	h := object.NewHash()
+	for i := 0; i < len(kv); i += 2 {
+		h.Set(kv[i], kv[i+1])
+	}
+	return h
+}
@@ -0,0 +1,116 @@
+package repl
+
+import (
+	"cathon/lexer"
+	"cathon/object"
+	"cathon/token"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const HELP = `Commands:
+  :help          show this help
+  :env           list the bindings in this session
+  :type <expr>   show the type of expr's value
+  :ast <expr>    show how expr parses
+  :tokens <expr> show the tokens of expr
+  :load <file>   run a script in this session
+  :reset         forget all bindings
+  :quit          leave the REPL
+`
+
+type command struct {
+	needsArg bool
+	// run returns false to end the REPL.
+	run func(s *session, arg string) bool
+}
+
+// commands are the REPL meta-commands, entered as :name and an argument.
+var commands = map[string]command{
+	"help": {run: func(s *session, arg string) bool {
+		io.WriteString(s.out, HELP)
+		return true
+	}},
+	"env": {run: func(s *session, arg string) bool {
+		s.locked(func() {
+			for _, name := range s.env.Names() {
+				value, _ := s.env.Get(name)
+				fmt.Fprintf(s.out, "%s = %s\n", name, value.Inspect())
+			}
+		})
+		return true
+	}},
+	"type": {needsArg: true, run: func(s *session, arg string) bool {
+		program, ok := s.parse(arg)
+		if !ok {
+			return true
+		}
+
+		evaluated := s.evalProgram(program)
+		switch {
+		case evaluated == nil:
+			io.WriteString(s.out, "no value\n")
+		case evaluated.Type() == object.ERROR_OBJ:
+			fmt.Fprintln(s.out, evaluated.Inspect())
+		default:
+			fmt.Fprintln(s.out, evaluated.Type())
+		}
+		return true
+	}},
+	"ast": {needsArg: true, run: func(s *session, arg string) bool {
+		program, ok := s.parse(arg)
+		if !ok {
+			return true
+		}
+
+		for _, stmt := range program.Statements {
+			fmt.Fprintln(s.out, stmt.String())
+		}
+		return true
+	}},
+	"tokens": {needsArg: true, run: func(s *session, arg string) bool {
+		l := lexer.New(arg)
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+			fmt.Fprintf(s.out, "%-9s %q\n", tok.Type, tok.Literal)
+		}
+		return true
+	}},
+	"load": {needsArg: true, run: func(s *session, arg string) bool {
+		src, err := os.ReadFile(arg)
+		if err != nil {
+			fmt.Fprintf(s.out, "could not load %s: %s\n", arg, err)
+			return true
+		}
+
+		s.eval(string(src))
+		return true
+	}},
+	"reset": {run: func(s *session, arg string) bool {
+		s.reset()
+		return true
+	}},
+	"quit": {run: func(s *session, arg string) bool {
+		return false
+	}},
+}
+
+// command runs a line starting with a colon and returns false when the
+// REPL should end.
+func (s *session) command(line string) bool {
+	name, arg, _ := strings.Cut(strings.TrimPrefix(line, ":"), " ")
+	arg = strings.TrimSpace(arg)
+
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(s.out, "unknown command :%s, type :help for a list\n", name)
+		return true
+	}
+	if cmd.needsArg && arg == "" {
+		fmt.Fprintf(s.out, ":%s needs an argument, see :help\n", name)
+		return true
+	}
+
+	return cmd.run(s, arg)
+}
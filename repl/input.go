@@ -0,0 +1,136 @@
+package repl
+
+import (
+	"bufio"
+	"cathon/lineedit"
+	"cathon/object"
+	"cathon/token"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// lineReader reads input one line at a time.
+type lineReader interface {
+	ReadLine(prompt string) (string, error)
+}
+
+// newLineReader uses a line editor when in and s.out are a terminal, and
+// reads plain lines otherwise.
+func (s *session) newLineReader(in io.Reader) lineReader {
+	f, ok := in.(*os.File)
+	if !ok || !lineedit.IsTerminal(f) {
+		return &scannerReader{scanner: bufio.NewScanner(in), out: s.out}
+	}
+	if out, ok := s.out.(*os.File); !ok || !lineedit.IsTerminal(out) {
+		return &scannerReader{scanner: bufio.NewScanner(in), out: s.out}
+	}
+
+	editor := lineedit.New(f, s.out)
+	editor.Complete = s.complete
+	if path, err := historyPath(); err == nil {
+		editor.LoadHistory(path)
+	}
+	return &terminalReader{f: f, editor: editor}
+}
+
+type scannerReader struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+func (r *scannerReader) ReadLine(prompt string) (string, error) {
+	io.WriteString(r.out, prompt)
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.scanner.Text(), nil
+}
+
+// terminalReader switches the terminal to raw mode only while a line is
+// edited, so scripts print as usual.
+type terminalReader struct {
+	f      *os.File
+	editor *lineedit.Editor
+}
+
+func (r *terminalReader) ReadLine(prompt string) (string, error) {
+	restore, err := lineedit.MakeRaw(r.f)
+	if err != nil {
+		return "", err
+	}
+	line, err := r.editor.ReadLine(prompt)
+	restore()
+
+	if err == nil {
+		r.editor.AddHistory(line)
+	}
+	return line, err
+}
+
+// historyPath is the file the REPL keeps its history in, creating its
+// directory if needed.
+func historyPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, "cathon")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "history"), nil
+}
+
+// complete returns the keywords, builtins and bound names that start with
+// word. A word with a dot completes the members of a module.
+func (s *session) complete(word string) []string {
+	builtins := s.interp.Builtins()
+
+	if i := strings.LastIndex(word, "."); i >= 0 {
+		owner, member := word[:i], word[i+1:]
+		obj, ok := s.env.Get(owner)
+		if !ok {
+			obj, ok = builtins[owner]
+		}
+		if !ok {
+			return nil
+		}
+
+		module, ok := obj.(*object.Module)
+		if !ok {
+			return nil
+		}
+
+		matches := []string{}
+		for name := range module.Members {
+			if strings.HasPrefix(name, member) {
+				matches = append(matches, owner+"."+name)
+			}
+		}
+		sort.Strings(matches)
+		return matches
+	}
+
+	names := token.Keywords()
+	for name := range builtins {
+		names = append(names, name)
+	}
+	names = append(names, s.env.Names()...)
+
+	seen := make(map[string]bool)
+	matches := []string{}
+	for _, name := range names {
+		if strings.HasPrefix(name, word) && !seen[name] {
+			seen[name] = true
+			matches = append(matches, name)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
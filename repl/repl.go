@@ -1,43 +1,177 @@
 package repl
 
 import (
-	"bufio"
+	"cathon/ast"
 	"cathon/evaluator"
 	"cathon/lexer"
+	"cathon/lineedit"
 	"cathon/object"
 	"cathon/parser"
+	"cathon/pretty"
+	"cathon/token"
 	"io"
+	"os"
+	"strings"
+	"sync"
 )
 
-const PROMPT = ">> "
+const (
+	PROMPT = ">> "
+	// CONTINUATION_PROMPT asks for more of an unfinished input.
+	CONTINUATION_PROMPT = ".. "
+)
+
+// session is the state one REPL keeps between inputs.
+type session struct {
+	out    io.Writer
+	env    *object.Environment
+	interp *evaluator.Interpreter
+
+	// parent, when set, is the environment that env is a child of.
+	parent *object.Environment
+	// lock, when set, is held while the session evaluates.
+	lock sync.Locker
+	// pretty formats the values of inputs.
+	pretty pretty.Options
+}
+
+func newSession(out io.Writer) *session {
+	s := &session{out: out}
+	s.reset()
+	return s
+}
+
+// reset forgets the session's bindings and starts a new interpreter.
+func (s *session) reset() {
+	if s.parent != nil {
+		s.env = object.NewEnclosedEnvironment(s.parent)
+	} else {
+		s.env = object.NewEnvironment()
+	}
+	s.interp = evaluator.New()
+	s.interp.Out = s.out
+}
 
 func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
-	env := object.NewEnvironment()
+	s := newSession(out)
+	s.pretty.Color = useColor(out)
+	s.run(s.newLineReader(in))
+}
 
+// useColor reports whether out is a terminal and the user hasn't asked
+// for no color with NO_COLOR.
+func useColor(out io.Writer) bool {
+	f, ok := out.(*os.File)
+	return ok && lineedit.IsTerminal(f) && os.Getenv("NO_COLOR") == ""
+}
+
+// run reads and evaluates inputs until lines ends or the user quits.
+func (s *session) run(lines lineReader) {
 	for {
-		print(PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
+		input, err := readInput(lines)
+		if err == lineedit.ErrInterrupted {
+			continue
+		}
+		if err != nil {
 			return
 		}
 
-		line := scanner.Text()
-		l := lexer.New(line)
-		p := parser.New(l)
-
-		program := p.ParseProgram()
-		if len(p.Errors()) != 0 {
-			printParserErrors(out, p.Errors())
+		if strings.HasPrefix(strings.TrimSpace(input), ":") {
+			if !s.command(strings.TrimSpace(input)) {
+				return
+			}
 			continue
 		}
 
-		evaluated := evaluator.Eval(program, env)
-		if evaluated != nil {
-			io.WriteString(out, evaluated.Inspect())
-			io.WriteString(out, "\n")
+		s.eval(input)
+	}
+}
+
+// readInput reads a line, and continuation lines while it is incomplete.
+// Input that ends early is returned as it is, so that it gets parse errors.
+func readInput(lines lineReader) (string, error) {
+	input, err := lines.ReadLine(PROMPT)
+	if err != nil {
+		return "", err
+	}
+
+	for incomplete(input) {
+		line, err := lines.ReadLine(CONTINUATION_PROMPT)
+		if err == lineedit.ErrInterrupted {
+			return "", err
+		}
+		if err != nil {
+			break
+		}
+		input += "\n" + line
+	}
+	return input, nil
+}
+
+// eval runs input and prints its value.
+func (s *session) eval(input string) {
+	program, ok := s.parse(input)
+	if !ok {
+		return
+	}
+
+	evaluated := s.evalProgram(program)
+	if evaluated != nil {
+		io.WriteString(s.out, pretty.Sprint(evaluated, s.pretty))
+		io.WriteString(s.out, "\n")
+	}
+}
+
+// evalProgram evaluates program in the session.
+func (s *session) evalProgram(program *ast.Program) object.Object {
+	var evaluated object.Object
+	s.locked(func() {
+		evaluated = s.interp.Eval(program, s.env)
+	})
+	return evaluated
+}
+
+// locked runs fn holding the session's lock, if it has one.
+func (s *session) locked(fn func()) {
+	if s.lock != nil {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+	}
+	fn()
+}
+
+// parse parses input, printing the errors if there are any.
+func (s *session) parse(input string) (*ast.Program, bool) {
+	l := lexer.New(input)
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParserErrors(s.out, p.Errors())
+		return nil, false
+	}
+	return program, true
+}
+
+// incomplete reports whether input leaves a string, parenthesis, bracket
+// or brace open, so that the REPL should read another line.
+func incomplete(input string) bool {
+	// Strings have no escapes, so every quote opens or closes one.
+	if strings.Count(input, `"`)%2 == 1 {
+		return true
+	}
+
+	depth := 0
+	l := lexer.New(input)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case token.LPAREN, token.LBRACKET, token.LBRACE:
+			depth++
+		case token.RPAREN, token.RBRACKET, token.RBRACE:
+			depth--
 		}
 	}
+	return depth > 0
 }
 
 const MONKEY_FACE = `            __,__
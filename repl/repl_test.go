@@ -2,6 +2,8 @@ package repl
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -27,3 +29,122 @@ func TestREPL(t *testing.T) {
 		}
 	}
 }
+
+func TestREPLMultiLine(t *testing.T) {
+	input := `let add = fn(a, b) {
+	a + b
+};
+add(
+1,
+2)
+let s = "two
+lines"; len(s)
+[1,
+2][1]
+`
+
+	tests := []string{"3", "9", "2"}
+
+	in := strings.NewReader(input)
+	var out bytes.Buffer
+	Start(in, &out)
+	outputs := strings.Split(strings.TrimSpace(withoutPrompts(out.String())), "\n")
+
+	if len(outputs) != len(tests) {
+		t.Fatalf("wrong number of outputs. expected=%d, got=%d (%q)",
+			len(tests), len(outputs), outputs)
+	}
+	for i, tt := range tests {
+		if outputs[i] != tt {
+			t.Errorf("tests[%d] - Wrong output. expected=%q, got=%q", i, tt, outputs[i])
+		}
+	}
+}
+
+func TestIncomplete(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`1 + 2`, false},
+		{`fn(x) {`, true},
+		{`fn(x) { x }`, false},
+		{`[1, (2`, true},
+		{`"abc`, true},
+		{`"(" + "["`, false},
+		{`}`, false},
+	}
+
+	for _, tt := range tests {
+		if got := incomplete(tt.input); got != tt.expected {
+			t.Errorf("incomplete(%q) = %t, want %t", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestCommands(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "lives.cat")
+	if err := os.WriteFile(script, []byte("let lives = 9;\nlives * 2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{":help", HELP},
+		{"let b = 2; let a = 1;\n:env", "a = 1\nb = 2\n"},
+		{":type 1.5", "FLOAT\n"},
+		{":type 1 / 0", "ERROR: division by zero\n"},
+		{":type let x = 1", "no value\n"},
+		{":ast 1 + 2 * 3", "(1 + (2 * 3))\n"},
+		{":ast let f = fn(x) {\nx\n}", "let f = fn(x) x;\n"},
+		{":tokens x.y >= 2", "IDENT     \"x\"\n.         \".\"\nIDENT     \"y\"\n>=        \">=\"\nINT       \"2\"\n"},
+		{":load " + script + "\nlives", "18\n9\n"},
+		{":load", ":load needs an argument, see :help\n"},
+		{"let a = 1;\n:reset\n:env", ""},
+		{":quit\n1", ""},
+		{":nope", "unknown command :nope, type :help for a list\n"},
+		{`{"name": "Nabi", "say": fn(x) { puts(x) }}`, "{\"name\": \"Nabi\", \"say\": fn(x) {...}}\n"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		Start(strings.NewReader(tt.input), &out)
+
+		if got := withoutPrompts(out.String()); got != tt.expected {
+			t.Errorf("wrong output for %q. expected=%q, got=%q",
+				tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestComplete(t *testing.T) {
+	s := newSession(&bytes.Buffer{})
+	s.eval("let lives = 9; let level = 1;")
+
+	tests := []struct {
+		word     string
+		expected []string
+	}{
+		{"le", []string{"len", "let", "level"}},
+		{"li", []string{"lives"}},
+		{"re", []string{"reduce", "rest", "return"}},
+		{"math.s", []string{"math.seed", "math.sin", "math.sqrt"}},
+		{"string.to", []string{}},
+		{"lives.x", nil},
+		{"zzz", []string{}},
+	}
+
+	for _, tt := range tests {
+		got := s.complete(tt.word)
+		if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("complete(%q) wrong. expected=%v, got=%v", tt.word, tt.expected, got)
+		}
+	}
+}
+
+func withoutPrompts(out string) string {
+	out = strings.ReplaceAll(out, PROMPT, "")
+	return strings.ReplaceAll(out, CONTINUATION_PROMPT, "")
+}
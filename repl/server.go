@@ -0,0 +1,157 @@
+package repl
+
+import (
+	"bufio"
+	"cathon/object"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+)
+
+// Server serves REPL sessions to a running host program, such as a game,
+// over a Unix socket or a localhost TCP port. Each connection gets its own
+// session, which works like Start with the connection as input and output.
+type Server struct {
+	// Env holds the host's bindings. Each session evaluates in a child of
+	// it, so sessions see the host's values but their own lets stay
+	// private. Nil gives every session an empty environment.
+	Env *object.Environment
+
+	// Lock is held while a session evaluates. The host holds the same lock
+	// while it runs its own scripts, so the two never run at once. Nil
+	// only keeps sessions from running at the same time as each other.
+	Lock sync.Locker
+
+	// Greeting is written to each connection before the first prompt.
+	Greeting string
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]bool
+	closed   bool
+}
+
+// Listen opens a listener for Serve. network is "unix", with address the
+// path of the socket, or "tcp", with address a loopback host and port such
+// as "127.0.0.1:7777". A stale socket file left by a crashed host is
+// replaced.
+func Listen(network, address string) (net.Listener, error) {
+	switch network {
+	case "unix":
+		l, err := net.Listen(network, address)
+		if err == nil {
+			return l, nil
+		}
+		// Replace the socket only when nothing answers on it, and never
+		// remove anything that isn't a socket.
+		if conn, dialErr := net.Dial(network, address); dialErr == nil {
+			conn.Close()
+			return nil, err
+		}
+		if fi, statErr := os.Lstat(address); statErr != nil || fi.Mode()&os.ModeSocket == 0 {
+			return nil, err
+		}
+		if removeErr := os.Remove(address); removeErr != nil {
+			return nil, err
+		}
+		return net.Listen(network, address)
+
+	case "tcp", "tcp4", "tcp6":
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return nil, fmt.Errorf("repl: %s is not a loopback address", host)
+		}
+		return net.Listen(network, address)
+
+	default:
+		return nil, fmt.Errorf("repl: unsupported network %s", network)
+	}
+}
+
+// ListenAndServe listens on network and address, as Listen does, and
+// serves sessions until Close is called.
+func (srv *Server) ListenAndServe(network, address string) error {
+	l, err := Listen(network, address)
+	if err != nil {
+		return err
+	}
+	return srv.Serve(l)
+}
+
+// Serve accepts connections on l, each in its own goroutine, until Close
+// is called. It then returns nil.
+func (srv *Server) Serve(l net.Listener) error {
+	srv.mu.Lock()
+	if srv.closed {
+		srv.mu.Unlock()
+		l.Close()
+		return nil
+	}
+	if srv.Lock == nil {
+		srv.Lock = &sync.Mutex{}
+	}
+	srv.listener = l
+	srv.conns = make(map[net.Conn]bool)
+	srv.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			srv.mu.Lock()
+			closed := srv.closed
+			srv.mu.Unlock()
+			if closed || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		srv.mu.Lock()
+		if srv.closed {
+			srv.mu.Unlock()
+			conn.Close()
+			return nil
+		}
+		srv.conns[conn] = true
+		srv.mu.Unlock()
+
+		go srv.handle(conn)
+	}
+}
+
+// Close stops accepting connections and ends every open session.
+func (srv *Server) Close() error {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	srv.closed = true
+	for conn := range srv.conns {
+		conn.Close()
+	}
+
+	if srv.listener == nil {
+		return nil
+	}
+	return srv.listener.Close()
+}
+
+func (srv *Server) handle(conn net.Conn) {
+	defer func() {
+		srv.mu.Lock()
+		delete(srv.conns, conn)
+		srv.mu.Unlock()
+		conn.Close()
+	}()
+
+	s := &session{out: conn, parent: srv.Env, lock: srv.Lock}
+	s.reset()
+
+	io.WriteString(conn, srv.Greeting)
+	s.run(&scannerReader{scanner: bufio.NewScanner(conn), out: conn})
+}
@@ -0,0 +1,166 @@
+package repl
+
+import (
+	"bufio"
+	"cathon/object"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testLocker records whether it is held, so scripts can check it.
+type testLocker struct {
+	mu   sync.Mutex
+	held bool
+}
+
+func (l *testLocker) Lock()   { l.mu.Lock(); l.held = true }
+func (l *testLocker) Unlock() { l.held = false; l.mu.Unlock() }
+
+func TestServer(t *testing.T) {
+	lock := &testLocker{}
+	env := object.NewEnvironment()
+	env.Set("score", &object.Integer{Value: 10})
+	env.Set("locked", &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		if lock.held {
+			return object.TRUE
+		}
+		return object.FALSE
+	}})
+
+	srv := &Server{Env: env, Lock: lock, Greeting: "cat game\n"}
+	l, err := Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %s", err)
+	}
+	done := make(chan error)
+	go func() { done <- srv.Serve(l) }()
+
+	first := dialSession(t, l.Addr())
+	if greeting := first.read(t); greeting != "cat game\n" {
+		t.Errorf("wrong greeting. got=%q", greeting)
+	}
+
+	tests := []struct {
+		session  *testSession
+		input    string
+		expected string
+	}{
+		{first, "score + 1", "11\n"},
+		{first, "locked()", "true\n"},
+		{first, "let score = 99; let mine = 1; score", "99\n"},
+		{first, "let f = fn(x) {\nx * 2\n}; f(score)", "198\n"},
+		{dialSession(t, l.Addr()), "score", "10\n"},
+		{dialSession(t, l.Addr()), "mine", "ERROR: identifier not found: mine\n"},
+		{first, ":reset", ""},
+		{first, "score", "10\n"},
+	}
+
+	for _, tt := range tests {
+		if tt.session != first && !tt.session.greeted {
+			tt.session.read(t)
+			tt.session.greeted = true
+		}
+		tt.session.write(t, tt.input)
+		if got := tt.session.read(t); got != tt.expected {
+			t.Errorf("wrong output for %q. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+
+	if score, _ := env.Get("score"); score.Inspect() != "10" {
+		t.Errorf("session changed the host binding. got=%s", score.Inspect())
+	}
+
+	if err := srv.Close(); err != nil {
+		t.Fatalf("Close failed: %s", err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Serve returned error after Close: %s", err)
+	}
+	if _, err := first.r.ReadByte(); err != io.EOF {
+		t.Errorf("session not ended by Close. got=%v", err)
+	}
+}
+
+func TestListen(t *testing.T) {
+	for _, address := range []string{"0.0.0.0:0", "192.168.1.1:0", "example.com:0"} {
+		if _, err := Listen("tcp", address); err == nil {
+			t.Errorf("Listen accepted non-loopback address %s", address)
+		}
+	}
+
+	l, err := Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Listen on localhost failed: %s", err)
+	}
+	l.Close()
+
+	file := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(file, []byte("keep"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Listen("unix", file); err == nil {
+		t.Errorf("Listen accepted the path of a regular file")
+	}
+	if data, err := os.ReadFile(file); err != nil || string(data) != "keep" {
+		t.Errorf("Listen removed a regular file: %v", err)
+	}
+
+	// A crashed host leaves its socket file behind.
+	path := filepath.Join(t.TempDir(), "repl.sock")
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	l, err = Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Listen didn't replace a stale socket: %s", err)
+	}
+	defer l.Close()
+
+	if _, err := Listen("unix", path); err == nil {
+		t.Errorf("Listen replaced a socket in use")
+	}
+}
+
+type testSession struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	greeted bool
+}
+
+func dialSession(t *testing.T, addr net.Addr) *testSession {
+	conn, err := net.Dial(addr.Network(), addr.String())
+	if err != nil {
+		t.Fatalf("Dial failed: %s", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &testSession{conn: conn, r: bufio.NewReader(conn)}
+}
+
+func (s *testSession) write(t *testing.T, input string) {
+	if _, err := io.WriteString(s.conn, input+"\n"); err != nil {
+		t.Fatalf("write failed: %s", err)
+	}
+}
+
+// read returns what the session writes up to its next prompt, leaving
+// out continuation prompts.
+func (s *testSession) read(t *testing.T) string {
+	var out strings.Builder
+	for !strings.HasSuffix(out.String(), PROMPT) {
+		b, err := s.r.ReadByte()
+		if err != nil {
+			t.Fatalf("read failed after %q: %s", out.String(), err)
+		}
+		out.WriteByte(b)
+	}
+	return withoutPrompts(out.String())
+}
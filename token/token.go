@@ -1,5 +1,7 @@
 package token
 
+import "sort"
+
 type TokenType string
 
 const (
@@ -8,6 +10,7 @@ const (
 
 	IDENT  = "IDENT"
 	INT    = "INT"
+	FLOAT  = "FLOAT"  // 1.5
 	STRING = "STRING" // "foobar"
 
 	ASSIGN   = "="
@@ -20,6 +23,7 @@ const (
 	COMMA     = ","
 	SEMICOLON = ";"
 	COLON     = ":"
+	DOT       = "."
 
 	LPAREN   = "("
 	RPAREN   = ")"
@@ -29,6 +33,8 @@ const (
 	RBRACKET = "]"
 	LT       = "<"
 	GT       = ">"
+	LTE      = "<="
+	GTE      = ">="
 
 	FUNCTION = "FUNCTION"
 	LET      = "LET"
@@ -37,6 +43,7 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	IN       = "IN"
 
 	EQ    = "=="
 	NOTEQ = "!="
@@ -46,6 +53,11 @@ const (
 type Token struct {
 	Type    TokenType
 	Literal string
+
+	// Line and Column locate the token's first character, counting from 1.
+	// Columns count runes, not bytes.
+	Line   int
+	Column int
 }
 
 var keywords = map[string]TokenType{
@@ -56,6 +68,17 @@ var keywords = map[string]TokenType{
 	"if":     IF,
 	"else":   ELSE,
 	"return": RETURN,
+	"in":     IN,
+}
+
+// Keywords returns the reserved words, sorted.
+func Keywords() []string {
+	words := make([]string, 0, len(keywords))
+	for word := range keywords {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
 }
 
 func LookupIdent(ident string) TokenType {
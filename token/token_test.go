@@ -14,6 +14,7 @@ func TestLookupIdent(t *testing.T) {
 		{"if", IF},
 		{"else", ELSE},
 		{"return", RETURN},
+		{"in", IN},
 		{"foobar", IDENT}, // Non-keyword, should return IDENT
 		{"x", IDENT},      // Single character identifier
 	}
@@ -25,3 +26,18 @@ func TestLookupIdent(t *testing.T) {
 		}
 	}
 }
+
+func TestKeywords(t *testing.T) {
+	expected := []string{"else", "false", "fn", "if", "in", "let", "return", "true"}
+
+	words := Keywords()
+	if len(words) != len(expected) {
+		t.Fatalf("wrong number of keywords. expected=%d, got=%d (%v)",
+			len(expected), len(words), words)
+	}
+	for i, word := range expected {
+		if words[i] != word {
+			t.Errorf("words[%d] wrong. expected=%q, got=%q", i, word, words[i])
+		}
+	}
+}
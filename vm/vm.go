@@ -0,0 +1,603 @@
+// Package vm runs the bytecode of package compiler on a stack machine. It
+// gets the same results as the evaluator, whose builtins it calls and
+// whose operators it applies to everything but integers.
+package vm
+
+import (
+	"cathon/code"
+	"cathon/compiler"
+	"cathon/evaluator"
+	"cathon/object"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	StackSize = 2048
+	MaxFrames = 1024
+)
+
+var (
+	NULL  = object.NULL
+	TRUE  = object.TRUE
+	FALSE = object.FALSE
+)
+
+// Integers are never changed once made, so the small ones arithmetic
+// yields most often are made once and shared.
+const (
+	minCachedInteger = -128
+	maxCachedInteger = 1024
+)
+
+var cachedIntegers [maxCachedInteger - minCachedInteger + 1]*object.Integer
+
+func init() {
+	for i := range cachedIntegers {
+		cachedIntegers[i] = &object.Integer{Value: int64(i + minCachedInteger)}
+	}
+}
+
+func newInteger(value int64) *object.Integer {
+	if value >= minCachedInteger && value <= maxCachedInteger {
+		return cachedIntegers[value-minCachedInteger]
+	}
+	return &object.Integer{Value: value}
+}
+
+// A Frame is a call of a closure. Its arguments and then its other locals
+// start at bp on the stack, right above the closure itself.
+type Frame struct {
+	cl *object.Closure
+	ip int
+	bp int
+}
+
+// cell holds a local that closures captured, so that the function and the
+// closures see the same variable, lets after the closure was made included.
+type cell struct {
+	value object.Object
+}
+
+func (c *cell) Type() object.ObjectType         { return "CELL" }
+func (c *cell) Equals(other object.Object) bool { return c == other }
+func (c *cell) Inspect() string {
+	if c.value == nil {
+		return "cell[]"
+	}
+	return fmt.Sprintf("cell[%s]", c.value.Inspect())
+}
+
+type VM struct {
+	// Out receives what puts and printf write. New sets it to os.Stdout.
+	Out io.Writer
+
+	constants []object.Object
+	main      *object.CompiledFunction
+
+	globals     []object.Object
+	globalNames []string
+
+	builtins       []object.Object
+	builtinsByName map[string]object.Object
+	interp         *evaluator.Interpreter
+
+	stack []object.Object
+	sp    int // Always points to the next value. Top of stack is stack[sp-1]
+
+	frames      []Frame
+	framesIndex int
+}
+
+// New returns a VM for bytecode, with the builtins and modules of a new
+// evaluator.Interpreter.
+func New(bytecode *compiler.Bytecode) *VM {
+	interp := evaluator.New()
+	builtinsByName := interp.Builtins()
+
+	builtins := make([]object.Object, len(bytecode.Builtins))
+	for i, name := range bytecode.Builtins {
+		builtins[i] = builtinsByName[name]
+	}
+
+	vm := &VM{
+		Out: os.Stdout,
+
+		constants: bytecode.Constants,
+		main:      &object.CompiledFunction{Instructions: bytecode.Instructions},
+
+		globals:     make([]object.Object, len(bytecode.Globals)),
+		globalNames: bytecode.Globals,
+
+		builtins:       builtins,
+		builtinsByName: builtinsByName,
+		interp:         interp,
+
+		stack:  make([]object.Object, StackSize),
+		frames: make([]Frame, MaxFrames),
+	}
+	interp.RunClosure = vm.runClosure
+	return vm
+}
+
+// SetGlobal sets the global name before Run, as env.Set does for the
+// evaluator. Names the program never uses are ignored.
+func (vm *VM) SetGlobal(name string, value object.Object) {
+	for i, global := range vm.globalNames {
+		if global == name {
+			vm.globals[i] = value
+		}
+	}
+}
+
+// Run runs the program and returns the value of its last statement, nil
+// if that is a let, or the *object.Error that stopped it.
+func (vm *VM) Run() object.Object {
+	vm.interp.Out = vm.Out
+
+	main := &object.Closure{Fn: vm.main}
+	vm.stack[0] = main
+	vm.sp = 1
+	vm.frames[0] = Frame{cl: main, bp: 1}
+	vm.framesIndex = 1
+
+	return vm.run(0)
+}
+
+// runClosure calls fn for a builtin such as map, running it until it
+// returns.
+func (vm *VM) runClosure(fn *object.Closure, args []object.Object) object.Object {
+	sp, framesIndex := vm.sp, vm.framesIndex
+	defer func() { vm.sp, vm.framesIndex = sp, framesIndex }()
+
+	if vm.sp+1+len(args) > StackSize {
+		return newError("stack overflow")
+	}
+	vm.stack[vm.sp] = fn
+	copy(vm.stack[vm.sp+1:], args)
+	vm.sp += 1 + len(args)
+
+	if err := vm.pushFrame(fn, len(args)); err != nil {
+		return err
+	}
+	return vm.run(framesIndex)
+}
+
+func (vm *VM) pushFrame(cl *object.Closure, numArgs int) *object.Error {
+	fn := cl.Fn
+	if numArgs != fn.NumParameters {
+		return newError("wrong number of arguments. got=%d, want=%d",
+			numArgs, fn.NumParameters)
+	}
+
+	bp := vm.sp - numArgs
+	if vm.framesIndex >= MaxFrames || bp+fn.NumLocals >= StackSize {
+		return newError("stack overflow")
+	}
+
+	// nil marks a local whose let hasn't run yet.
+	for i := vm.sp; i < bp+fn.NumLocals; i++ {
+		vm.stack[i] = nil
+	}
+	for _, i := range fn.Cells {
+		vm.stack[bp+i] = &cell{value: vm.stack[bp+i]}
+	}
+
+	vm.frames[vm.framesIndex] = Frame{cl: cl, bp: bp}
+	vm.framesIndex++
+	vm.sp = bp + fn.NumLocals
+	return nil
+}
+
+// run runs the frames above base until the one at base returns, and
+// returns its value or the error that stopped it.
+func (vm *VM) run(base int) object.Object {
+	frame := &vm.frames[vm.framesIndex-1]
+	ins := frame.cl.Fn.Instructions
+	ip := frame.ip
+
+	for {
+		// Everything but OpPop and the returns pushes at most one value
+		// more than it pops.
+		if vm.sp >= StackSize {
+			return newError("stack overflow")
+		}
+
+		op := code.Opcode(ins[ip])
+		ip++
+
+		switch op {
+		case code.OpConstant:
+			index := code.ReadUint16(ins[ip:])
+			ip += 2
+			vm.push(vm.constants[index])
+
+		case code.OpTrue:
+			vm.push(TRUE)
+
+		case code.OpFalse:
+			vm.push(FALSE)
+
+		case code.OpNull:
+			vm.push(NULL)
+
+		case code.OpPop:
+			vm.sp--
+
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv,
+			code.OpEqual, code.OpNotEqual,
+			code.OpLessThan, code.OpGreaterThan, code.OpLessEqual, code.OpGreaterEqual,
+			code.OpIn:
+			right := vm.stack[vm.sp-1]
+			left := vm.stack[vm.sp-2]
+			vm.sp -= 2
+
+			result := executeInfix(op, left, right)
+			if isError(result) {
+				return result
+			}
+			vm.push(result)
+
+		case code.OpMinus:
+			right := vm.stack[vm.sp-1]
+			var result object.Object
+			if integer, ok := right.(*object.Integer); ok {
+				result = newInteger(-integer.Value)
+			} else {
+				result = evaluator.Prefix("-", right)
+			}
+			if isError(result) {
+				return result
+			}
+			vm.stack[vm.sp-1] = result
+
+		case code.OpBang:
+			vm.stack[vm.sp-1] = nativeBoolToBooleanObject(!isTruthy(vm.stack[vm.sp-1]))
+
+		case code.OpJump:
+			ip = int(code.ReadUint16(ins[ip:]))
+
+		case code.OpJumpNotTruthy:
+			pos := int(code.ReadUint16(ins[ip:]))
+			ip += 2
+
+			vm.sp--
+			if !isTruthy(vm.stack[vm.sp]) {
+				ip = pos
+			}
+
+		case code.OpGetGlobal:
+			index := code.ReadUint16(ins[ip:])
+			ip += 2
+
+			value := vm.globals[index]
+			if value == nil {
+				// Like the evaluator, fall back to the builtin of the name
+				// until a let shadows it.
+				name := vm.globalNames[index]
+				builtin, ok := vm.builtinsByName[name]
+				if !ok {
+					return newError("identifier not found: " + name)
+				}
+				value = builtin
+			}
+			vm.push(value)
+
+		case code.OpSetGlobal:
+			index := code.ReadUint16(ins[ip:])
+			ip += 2
+
+			vm.sp--
+			vm.globals[index] = vm.stack[vm.sp]
+
+		case code.OpGetLocal:
+			index := int(ins[ip])
+			ip++
+
+			value := vm.stack[frame.bp+index]
+			if value == nil {
+				return newError("identifier not found: " + frame.cl.Fn.Locals[index])
+			}
+			vm.push(value)
+
+		case code.OpSetLocal:
+			index := int(ins[ip])
+			ip++
+
+			vm.sp--
+			vm.stack[frame.bp+index] = vm.stack[vm.sp]
+
+		case code.OpGetCell:
+			index := int(ins[ip])
+			ip++
+
+			value := vm.stack[frame.bp+index].(*cell).value
+			if value == nil {
+				return newError("identifier not found: " + frame.cl.Fn.Locals[index])
+			}
+			vm.push(value)
+
+		case code.OpSetCell:
+			index := int(ins[ip])
+			ip++
+
+			vm.sp--
+			vm.stack[frame.bp+index].(*cell).value = vm.stack[vm.sp]
+
+		case code.OpGetFree:
+			index := int(ins[ip])
+			ip++
+
+			value := frame.cl.Free[index].(*cell).value
+			if value == nil {
+				return newError("identifier not found: " + frame.cl.Fn.Free[index].Name)
+			}
+			vm.push(value)
+
+		case code.OpGetBuiltin:
+			index := int(ins[ip])
+			ip++
+			vm.push(vm.builtins[index])
+
+		case code.OpArray:
+			n := int(code.ReadUint16(ins[ip:]))
+			ip += 2
+
+			elements := make([]object.Object, n)
+			copy(elements, vm.stack[vm.sp-n:vm.sp])
+			vm.sp -= n
+			vm.push(&object.Array{Elements: elements})
+
+		case code.OpHash:
+			n := int(code.ReadUint16(ins[ip:]))
+			ip += 2
+
+			hash := object.NewHash()
+			for i := vm.sp - 2*n; i < vm.sp; i += 2 {
+				key, value := vm.stack[i], vm.stack[i+1]
+				if !object.IsHashable(key) {
+					return newError("unusable as hash key: %s", key.Type())
+				}
+				hash.Set(key, value)
+			}
+			vm.sp -= 2 * n
+			vm.push(hash)
+
+		case code.OpIndex:
+			index := vm.stack[vm.sp-1]
+			left := vm.stack[vm.sp-2]
+			vm.sp -= 2
+
+			var result object.Object
+			if array, ok := left.(*object.Array); ok {
+				if i, ok := index.(*object.Integer); ok {
+					result = NULL
+					if i.Value >= 0 && i.Value < int64(len(array.Elements)) {
+						result = array.Elements[i.Value]
+					}
+				}
+			}
+			if result == nil {
+				result = evaluator.Index(left, index)
+			}
+			if isError(result) {
+				return result
+			}
+			vm.push(result)
+
+		case code.OpSlice:
+			bits := int(ins[ip])
+			ip++
+
+			var bounds [3]object.Object
+			for i := 2; i >= 0; i-- {
+				if bits&(1<<i) != 0 {
+					vm.sp--
+					bounds[i] = vm.stack[vm.sp]
+				}
+			}
+			vm.sp--
+			result := evaluator.Slice(vm.stack[vm.sp], bounds[0], bounds[1], bounds[2])
+			if isError(result) {
+				return result
+			}
+			vm.push(result)
+
+		case code.OpGetField:
+			name := vm.constants[code.ReadUint16(ins[ip:])].(*object.String).Value
+			ip += 2
+
+			result := evaluator.Field(vm.stack[vm.sp-1], name)
+			if isError(result) {
+				return result
+			}
+			vm.stack[vm.sp-1] = result
+
+		case code.OpSetField:
+			name := vm.constants[code.ReadUint16(ins[ip:])].(*object.String).Value
+			ip += 2
+
+			value := vm.stack[vm.sp-1]
+			left := vm.stack[vm.sp-2]
+			vm.sp -= 2
+
+			result := evaluator.SetField(left, name, value)
+			if isError(result) {
+				return result
+			}
+			vm.push(result)
+
+		case code.OpCallMethod:
+			name := vm.constants[code.ReadUint16(ins[ip:])].(*object.String).Value
+			numArgs := int(ins[ip+2])
+			ip += 3
+			frame.ip = ip
+
+			args := make([]object.Object, numArgs)
+			copy(args, vm.stack[vm.sp-numArgs:vm.sp])
+			left := vm.stack[vm.sp-numArgs-1]
+			vm.sp -= numArgs + 1
+
+			result := evaluator.CallMethod(left, name, args)
+			if isError(result) {
+				return result
+			}
+			vm.push(result)
+
+		case code.OpCall:
+			numArgs := int(ins[ip])
+			ip++
+			frame.ip = ip
+
+			switch fn := vm.stack[vm.sp-1-numArgs].(type) {
+			case *object.Closure:
+				if err := vm.pushFrame(fn, numArgs); err != nil {
+					return err
+				}
+				frame = &vm.frames[vm.framesIndex-1]
+				ins = fn.Fn.Instructions
+				ip = 0
+
+			case *object.Builtin:
+				// Builtins may keep their arguments, and callbacks they
+				// run reuse the stack.
+				args := make([]object.Object, numArgs)
+				copy(args, vm.stack[vm.sp-numArgs:vm.sp])
+
+				result := fn.Fn(args...)
+				if isError(result) {
+					return result
+				}
+				vm.sp -= numArgs + 1
+				vm.push(result)
+
+			default:
+				return newError("not a function: %s", fn.Type())
+			}
+
+		case code.OpReturnValue, code.OpReturn:
+			var result object.Object
+			if op == code.OpReturnValue {
+				result = vm.stack[vm.sp-1]
+			} else if vm.framesIndex > 1 {
+				// A function whose body yields nothing returns NULL, but
+				// the program then has no value.
+				result = NULL
+			}
+
+			vm.sp = frame.bp - 1
+			vm.framesIndex--
+			if vm.framesIndex == base {
+				return result
+			}
+			vm.push(result)
+
+			frame = &vm.frames[vm.framesIndex-1]
+			ins = frame.cl.Fn.Instructions
+			ip = frame.ip
+
+		case code.OpClosure:
+			fn := vm.constants[code.ReadUint16(ins[ip:])].(*object.CompiledFunction)
+			ip += 2
+
+			free := make([]object.Object, len(fn.Free))
+			for i, v := range fn.Free {
+				if v.Local {
+					free[i] = vm.stack[frame.bp+v.Index]
+				} else {
+					free[i] = frame.cl.Free[v.Index]
+				}
+			}
+			vm.push(&object.Closure{Fn: fn, Free: free})
+
+		case code.OpFail:
+			message := vm.constants[code.ReadUint16(ins[ip:])].(*object.String).Value
+			return newError("%s", message)
+
+		default:
+			return newError("unknown opcode %d", op)
+		}
+	}
+}
+
+func (vm *VM) push(o object.Object) {
+	vm.stack[vm.sp] = o
+	vm.sp++
+}
+
+var infixOperators = map[code.Opcode]string{
+	code.OpAdd:          "+",
+	code.OpSub:          "-",
+	code.OpMul:          "*",
+	code.OpDiv:          "/",
+	code.OpEqual:        "==",
+	code.OpNotEqual:     "!=",
+	code.OpLessThan:     "<",
+	code.OpGreaterThan:  ">",
+	code.OpLessEqual:    "<=",
+	code.OpGreaterEqual: ">=",
+	code.OpIn:           "in",
+}
+
+// executeInfix does integer arithmetic and comparisons itself and leaves
+// everything else, division by zero included, to the evaluator.
+func executeInfix(op code.Opcode, left, right object.Object) object.Object {
+	l, ok := left.(*object.Integer)
+	if !ok {
+		return evaluator.Infix(infixOperators[op], left, right)
+	}
+	r, ok := right.(*object.Integer)
+	if !ok {
+		return evaluator.Infix(infixOperators[op], left, right)
+	}
+
+	switch op {
+	case code.OpAdd:
+		return newInteger(l.Value + r.Value)
+	case code.OpSub:
+		return newInteger(l.Value - r.Value)
+	case code.OpMul:
+		return newInteger(l.Value * r.Value)
+	case code.OpDiv:
+		if r.Value != 0 {
+			return newInteger(l.Value / r.Value)
+		}
+	case code.OpEqual:
+		return nativeBoolToBooleanObject(l.Value == r.Value)
+	case code.OpNotEqual:
+		return nativeBoolToBooleanObject(l.Value != r.Value)
+	case code.OpLessThan:
+		return nativeBoolToBooleanObject(l.Value < r.Value)
+	case code.OpGreaterThan:
+		return nativeBoolToBooleanObject(l.Value > r.Value)
+	case code.OpLessEqual:
+		return nativeBoolToBooleanObject(l.Value <= r.Value)
+	case code.OpGreaterEqual:
+		return nativeBoolToBooleanObject(l.Value >= r.Value)
+	}
+
+	return evaluator.Infix(infixOperators[op], left, right)
+}
+
+func nativeBoolToBooleanObject(input bool) *object.Boolean {
+	if input {
+		return TRUE
+	}
+	return FALSE
+}
+
+func isTruthy(obj object.Object) bool {
+	return obj != NULL && obj != FALSE
+}
+
+func newError(format string, a ...interface{}) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, a...)}
+}
+
+func isError(obj object.Object) bool {
+	if obj != nil {
+		return obj.Type() == object.ERROR_OBJ
+	}
+	return false
+}
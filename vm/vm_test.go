@@ -0,0 +1,267 @@
+package vm
+
+import (
+	"bytes"
+	"cathon/compiler"
+	"cathon/evaluator"
+	"cathon/lexer"
+	"cathon/object"
+	"cathon/parser"
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"io"
+	"strconv"
+	"testing"
+)
+
+// evaluatorInputs returns the programs of the evaluator's tests: the input
+// fields of their tables.
+func evaluatorInputs(t *testing.T) []string {
+	t.Helper()
+
+	file, err := goparser.ParseFile(token.NewFileSet(), "../evaluator/evaluator_test.go", nil, 0)
+	if err != nil {
+		t.Fatalf("parsing evaluator tests: %s", err)
+	}
+
+	var inputs []string
+	ast.Inspect(file, func(n ast.Node) bool {
+		lit, ok := n.(*ast.CompositeLit)
+		if !ok {
+			return true
+		}
+		array, ok := lit.Type.(*ast.ArrayType)
+		if !ok {
+			return true
+		}
+		fields, ok := array.Elt.(*ast.StructType)
+		if !ok || len(fields.Fields.List) == 0 || fields.Fields.List[0].Names[0].Name != "input" {
+			return true
+		}
+
+		for _, elt := range lit.Elts {
+			row, ok := elt.(*ast.CompositeLit)
+			if !ok || len(row.Elts) == 0 {
+				continue
+			}
+			first := row.Elts[0]
+			if kv, ok := first.(*ast.KeyValueExpr); ok {
+				first = kv.Value
+			}
+			basic, ok := first.(*ast.BasicLit)
+			if !ok || basic.Kind != token.STRING {
+				continue
+			}
+			input, err := strconv.Unquote(basic.Value)
+			if err != nil {
+				t.Fatalf("unquoting %s: %s", basic.Value, err)
+			}
+			inputs = append(inputs, input)
+		}
+		return true
+	})
+	return inputs
+}
+
+// TestMatchesEvaluator runs the programs of the evaluator's tests on the
+// vm and expects the same results and output.
+func TestMatchesEvaluator(t *testing.T) {
+	inputs := evaluatorInputs(t)
+	if len(inputs) < 100 {
+		t.Fatalf("found only %d inputs in the evaluator's tests", len(inputs))
+	}
+
+	for _, input := range inputs {
+		// Some inputs are there for their parser errors.
+		p := parser.New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			continue
+		}
+		testMatchesEvaluator(t, input)
+	}
+}
+
+func TestClosures(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let f = fn(x) { fn(y) { fn(z) { x + y + z } } }; f(1)(2)(3)", "6"},
+		{"let adders = map([1, 2, 3], fn(x) { fn(y) { x + y } }); map(adders, fn(f) { f(10) })", "[11, 12, 13]"},
+		// Closures see lets that follow them, in functions too.
+		{"let f = fn() { let g = fn() { h() }; let h = fn() { 42 }; g() }; f()", "42"},
+		{"let f = fn() { let a = 1; let g = fn() { a }; let a = 2; g() }; f()", "2"},
+		{"let x = 1; let f = fn() { x }; let x = 2; f()", "2"},
+		{"let counter = fn() { let n = 0; fn() { let n = n + 1; n } }; let c = counter(); c(); c()", "1"},
+		{"let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } };" +
+			"let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } }; even(10)", "true"},
+		{"let f = fn(n) { if (n > 0) { return f(n - 1) }; \"done\" }; f(3)", "done"},
+		{"let f = fn() { let y = x; let x = 1; y }; let x = 5; f()", "5"},
+		{"fn(x) { x }", "fn(x) {\nx\n}"},
+	}
+
+	for _, tt := range tests {
+		got := testMatchesEvaluator(t, tt.input)
+		if got == nil || got.Inspect() != tt.expected {
+			t.Errorf("%q: got=%s, want %s", tt.input, describe(got), tt.expected)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let f = fn() { x }; f()", "identifier not found: x"},
+		{"let f = fn() { g() }; let g = fn() { y }; f()", "identifier not found: y"},
+		{"let f = fn(c) { if (c) { let x = 1 }; x }; f(false)", "identifier not found: x"},
+		{"let f = fn() { let g = fn() { a }; g() }; f()", "identifier not found: a"},
+		{"let f = fn() { let g = fn() { a }; let r = g(); let a = 1; r }; f()", "identifier not found: a"},
+		{"let f = fn(a, b) { a }; f(1)", "wrong number of arguments. got=1, want=2"},
+		{"1(2)", "not a function: INTEGER"},
+		{"map([1, 2], fn(x) { x + \"a\" })", "type mismatch: INTEGER + STRING"},
+		{"let loop = fn(n) { loop(n + 1) }; loop(0)", "stack overflow"},
+		{"let loop = fn(n) { map([n], fn(x) { loop(x + 1) }) }; loop(0)", "stack overflow"},
+	}
+
+	for _, tt := range tests {
+		got := run(t, tt.input, nil)
+		err, ok := got.(*object.Error)
+		if !ok {
+			t.Errorf("%q: got=%s, want an error", tt.input, describe(got))
+			continue
+		}
+		if err.Message != tt.expected {
+			t.Errorf("%q: wrong error. want=%q, got=%q", tt.input, tt.expected, err.Message)
+		}
+	}
+}
+
+func TestRunClosureAfterError(t *testing.T) {
+	machine := New(compile(t, "let f = fn(x) { if (x) { y } else { x } }; 1"))
+	machine.Run()
+	f := machine.globals[0].(*object.Closure)
+
+	sp, framesIndex := machine.sp, machine.framesIndex
+	if got := machine.runClosure(f, []object.Object{object.TRUE}); describe(got) != "ERROR ERROR: identifier not found: y" {
+		t.Errorf("got=%s", describe(got))
+	}
+	if machine.sp != sp || machine.framesIndex != framesIndex {
+		t.Errorf("runClosure left sp=%d and framesIndex=%d, want %d and %d",
+			machine.sp, machine.framesIndex, sp, framesIndex)
+	}
+	if got := machine.runClosure(f, []object.Object{object.FALSE}); got != object.FALSE {
+		t.Errorf("got=%s", describe(got))
+	}
+}
+
+func TestSetGlobal(t *testing.T) {
+	var out bytes.Buffer
+	bytecode := compile(t, "puts(args[0]); len(args)")
+	machine := New(bytecode)
+	machine.Out = &out
+	machine.SetGlobal("args", &object.Array{Elements: []object.Object{&object.String{Value: "cat"}}})
+	machine.SetGlobal("unused", object.NULL)
+
+	if got := machine.Run(); describe(got) != "INTEGER 1" {
+		t.Errorf("got=%s", describe(got))
+	}
+	if out.String() != "cat\n" {
+		t.Errorf("wrong output %q", out.String())
+	}
+}
+
+const fibonacci = `
+let fib = fn(n) { if (n < 2) { return n; } fib(n - 1) + fib(n - 2) };
+fib(20)
+`
+
+const higherOrder = `
+let upto = fn(n, acc) { if (n == 0) { acc } else { upto(n - 1, push(acc, n)) } };
+let squares = map(upto(500, []), fn(x) { x * x });
+let odd = filter(squares, fn(x) { x - x / 2 * 2 == 1 });
+reduce(odd, fn(a, b) { a + b })
+`
+
+func BenchmarkEvaluatorFibonacci(b *testing.B) { benchmarkEvaluator(b, fibonacci) }
+func BenchmarkVMFibonacci(b *testing.B)        { benchmarkVM(b, fibonacci) }
+
+func BenchmarkEvaluatorHigherOrder(b *testing.B) { benchmarkEvaluator(b, higherOrder) }
+func BenchmarkVMHigherOrder(b *testing.B)        { benchmarkVM(b, higherOrder) }
+
+func benchmarkEvaluator(b *testing.B, input string) {
+	program := parser.New(lexer.New(input)).ParseProgram()
+
+	for i := 0; i < b.N; i++ {
+		if result := evaluator.Eval(program, object.NewEnvironment()); isError(result) {
+			b.Fatal(result.Inspect())
+		}
+	}
+}
+
+func benchmarkVM(b *testing.B, input string) {
+	bytecode, err := compiler.Compile(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for i := 0; i < b.N; i++ {
+		if result := New(bytecode).Run(); isError(result) {
+			b.Fatal(result.Inspect())
+		}
+	}
+}
+
+// testMatchesEvaluator runs input on the evaluator and on the vm, checks
+// that the results and output match and returns the vm's result.
+func testMatchesEvaluator(t *testing.T, input string) object.Object {
+	t.Helper()
+
+	var want, got bytes.Buffer
+	interp := evaluator.New()
+	interp.Out = &want
+	expected := interp.Eval(parser.New(lexer.New(input)).ParseProgram(), object.NewEnvironment())
+
+	result := run(t, input, &got)
+	if describe(result) != describe(expected) || got.String() != want.String() {
+		t.Errorf("%q: vm differs from evaluator.\nwant=%s, output %q\ngot=%s, output %q",
+			input, describe(expected), want.String(), describe(result), got.String())
+	}
+	return result
+}
+
+func run(t *testing.T, input string, out io.Writer) object.Object {
+	t.Helper()
+
+	machine := New(compile(t, input))
+	if out != nil {
+		machine.Out = out
+	}
+	return machine.Run()
+}
+
+func compile(t *testing.T, input string) *compiler.Bytecode {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("%q: parser errors: %v", input, p.Errors())
+	}
+
+	bytecode, err := compiler.Compile(program)
+	if err != nil {
+		t.Fatalf("%q: compiler error: %s", input, err)
+	}
+	return bytecode
+}
+
+func describe(obj object.Object) string {
+	if obj == nil {
+		return "nil"
+	}
+	return string(obj.Type()) + " " + obj.Inspect()
+}
@@ -0,0 +1,66 @@
+// Package wire reads and writes messages framed by a Content-Length
+// header, the base protocol that LSP and DAP share.
+package wire
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+)
+
+// MaxContentLength bounds the bodies Read accepts, so a bad header can't
+// make it allocate without limit.
+const MaxContentLength = 16 << 20
+
+// ErrTooLarge is returned, wrapped, by Read for a body longer than
+// MaxContentLength. The body is skipped, so the next message can still be
+// read.
+var ErrTooLarge = errors.New("message too large")
+
+type Reader struct {
+	r *textproto.Reader
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: textproto.NewReader(bufio.NewReader(r))}
+}
+
+// Read returns the body of the next message, or io.EOF once the input
+// ends between messages.
+func (r *Reader) Read() ([]byte, error) {
+	header, err := r.r.ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF && len(header) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("bad Content-Length %q", header.Get("Content-Length"))
+	}
+	if length > MaxContentLength {
+		// If the input ends first, the next Read reports it.
+		io.CopyN(io.Discard, r.r.R, length)
+		return nil, fmt.Errorf("%w: %d bytes", ErrTooLarge, length)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r.r.R, body); err != nil {
+		return nil, fmt.Errorf("reading body: %w", err)
+	}
+	return body, nil
+}
+
+// Write writes body to w as one message.
+func Write(w io.Writer, body []byte) error {
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err := w.Write(body)
+	return err
+}
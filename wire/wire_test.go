@@ -0,0 +1,69 @@
+package wire
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadWrite(t *testing.T) {
+	var buf bytes.Buffer
+	for _, body := range []string{`{"a":1}`, ``, `"고양이"`} {
+		if err := Write(&buf, []byte(body)); err != nil {
+			t.Fatalf("Write: %s", err)
+		}
+	}
+
+	r := NewReader(&buf)
+	for _, want := range []string{`{"a":1}`, ``, `"고양이"`} {
+		body, err := r.Read()
+		if err != nil {
+			t.Fatalf("Read: %s", err)
+		}
+		if string(body) != want {
+			t.Errorf("body=%q, want %q", body, want)
+		}
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("Read at the end returned %v, want io.EOF", err)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Content-Length: x\r\n\r\n", `bad Content-Length "x"`},
+		{"Content-Length: -1\r\n\r\n", `bad Content-Length "-1"`},
+		{"Content-Length: 99999999999999999999\r\n\r\n", `bad Content-Length "99999999999999999999"`},
+		{"Content-Length: 9999999999999999\r\n\r\n{}", "message too large: 9999999999999999 bytes"},
+		{"Content-Length: 5\r\n\r\n{}", "reading body: unexpected EOF"},
+		{"Content-Length: 2\r\n", "reading header: EOF"},
+	}
+
+	for _, tt := range tests {
+		_, err := NewReader(strings.NewReader(tt.input)).Read()
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("%q: got error %v, want %q", tt.input, err, tt.expected)
+		}
+	}
+}
+
+func TestReadSkipsLargeBodies(t *testing.T) {
+	large := strings.Repeat("x", MaxContentLength+1)
+	var buf bytes.Buffer
+	Write(&buf, []byte(large))
+	Write(&buf, []byte("{}"))
+
+	r := NewReader(&buf)
+	if _, err := r.Read(); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("got error %v, want ErrTooLarge", err)
+	}
+	body, err := r.Read()
+	if err != nil || string(body) != "{}" {
+		t.Errorf("next message is %q, %v", body, err)
+	}
+}